@@ -0,0 +1,102 @@
+package sqlhelper
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Finding AuditSQL 在 SQL 结构部分发现的可疑特征
+type Finding struct {
+	Offset  int    // 特征在 SQL 中的字节偏移
+	Pattern string // 命中的特征，如 "union select"
+}
+
+// auditRule AuditSQL 检查的一类特征，re 作用于 auditView 的结果
+type auditRule struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+// auditRules AuditSQL 检查的特征：联合查询、堆叠查询、时间盲注、文件读写、命令执行和恒真条件
+// 字符串字面量在检查前被替换为单个 '，因此 or '=' 对应 OR 'a'='a' 这类比较两个字面量的条件
+var auditRules = []auditRule{
+	{"union select", regexp.MustCompile(`\bunion (?:(?:all|distinct) )?\(? ?select\b`)},
+	{"stacked query", regexp.MustCompile(`; ?(?:drop|delete|insert|update|truncate|alter|create|grant|exec|execute|shutdown)\b`)},
+	{"sleep(", regexp.MustCompile(`\b(?:sleep|pg_sleep) ?\(`)},
+	{"benchmark(", regexp.MustCompile(`\bbenchmark ?\(`)},
+	{"waitfor delay", regexp.MustCompile(`\bwaitfor (?:delay|time)\b`)},
+	{"load_file(", regexp.MustCompile(`\bload_file ?\(`)},
+	{"into outfile", regexp.MustCompile(`\binto (?:outfile|dumpfile)\b`)},
+	{"xp_cmdshell", regexp.MustCompile(`\bxp_cmdshell\b`)},
+	{"or tautology", regexp.MustCompile(`\bor (?:' ?= ?'|(\d+) ?= ?(\d+)\b)`)},
+}
+
+// AuditSQL 检查已经展开的完整 SQL（而不是带占位符的模板），返回出现在字符串字面量、引号标识符和注释之外的可疑特征，
+// 如结构部分中的 UNION SELECT、; DROP、SLEEP(、OR 1=1 等，按位置排序；没有发现时返回 nil
+// 与参数清理不同，它检查的是拼装后的语句，用作执行前的最后一道检查，发现查询构造器拼接错误等问题
+// 关键字不区分大小写，关键字之间的空白和注释视为一个空格。字符串按 DialectMySQL 的规则扫描
+func AuditSQL(sql string) []Finding {
+	return AuditSQLFor(sql, DialectMySQL)
+}
+
+// AuditSQLFor 与 AuditSQL 相同，但按方言 d 的转义和注释规则扫描
+func AuditSQLFor(sql string, d Dialect) []Finding {
+	view, offsets := auditView(sql, d)
+	var findings []Finding
+	for _, rule := range auditRules {
+		for _, m := range rule.re.FindAllStringSubmatchIndex(view, -1) {
+			// 数字比较只在两边相等时才是恒真条件
+			if len(m) >= 6 && m[2] >= 0 && view[m[2]:m[3]] != view[m[4]:m[5]] {
+				continue
+			}
+			findings = append(findings, Finding{Offset: offsets[m[0]], Pattern: rule.pattern})
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Offset < findings[j].Offset })
+	return findings
+}
+
+// auditView 返回 SQL 结构部分的检查视图：ASCII 字母转为小写，字符串和引号标识符替换为各自的单个引号字符，
+// 注释和连续空白替换为一个空格；offsets[i] 为 view[i] 在 sql 中的字节偏移
+func auditView(sql string, d Dialect) (string, []int) {
+	var b strings.Builder
+	offsets := make([]int, 0, len(sql))
+	space := func(i int) {
+		if s := b.String(); len(s) > 0 && s[len(s)-1] != ' ' {
+			b.WriteByte(' ')
+			offsets = append(offsets, i)
+		}
+	}
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			b.WriteByte(c)
+			offsets = append(offsets, i)
+			i = skipNonCode(sql, i, d)
+			continue
+		case c == '$' && d == DialectPostgres:
+			if j := skipDollarQuoted(sql, i); j > i+1 {
+				b.WriteByte('\'')
+				offsets = append(offsets, i)
+				i = j
+				continue
+			}
+		case isSpaceByte(c):
+			space(i)
+			i++
+			continue
+		case c == '-' || c == '#' || c == '/':
+			if j := skipNonCode(sql, i, d); j > i {
+				space(i)
+				i = j
+				continue
+			}
+		}
+		b.WriteByte(lowerASCII(c))
+		offsets = append(offsets, i)
+		i++
+	}
+	return b.String(), offsets
+}
@@ -0,0 +1,62 @@
+package sqlhelper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAuditSQL(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []Finding
+	}{
+		{"正常查询", "SELECT * FROM users WHERE id = 1 AND name = 'john'", nil},
+		{"字符串中的特征不报告", "SELECT * FROM t WHERE note = '1 UNION SELECT password; DROP TABLE t -- or 1=1'", nil},
+		{"注释中的特征不报告", "SELECT 1 -- union select\n/* sleep(5) */ FROM t", nil},
+		{"引号标识符中的特征不报告", "SELECT `union select` FROM t", nil},
+		{"结构部分的联合查询", "SELECT name FROM t WHERE id = 1 UNION SELECT password FROM users",
+			[]Finding{{Offset: 32, Pattern: "union select"}}},
+		{"注释和换行分隔的关键字", "SELECT 1 UNION/**/ALL\nSELECT 2", []Finding{{Offset: 9, Pattern: "union select"}}},
+		{"堆叠查询", "SELECT 1; DROP TABLE users", []Finding{{Offset: 8, Pattern: "stacked query"}}},
+		{"时间盲注", "SELECT * FROM t WHERE id = 1 AND SLEEP(5)", []Finding{{Offset: 33, Pattern: "sleep("}}},
+		{"数字恒真条件", "SELECT * FROM t WHERE a = 'x' OR 1=1", []Finding{{Offset: 30, Pattern: "or tautology"}}},
+		{"字面量恒真条件", "SELECT * FROM t WHERE a = '' OR 'a' = 'a'", []Finding{{Offset: 29, Pattern: "or tautology"}}},
+		{"不相等的数字比较不报告", "SELECT * FROM t WHERE a = 1 OR 1 = 2", nil},
+		{"按位置排序", "SELECT 1 OR 2=2; DELETE FROM t UNION SELECT 1",
+			[]Finding{{Offset: 9, Pattern: "or tautology"}, {Offset: 15, Pattern: "stacked query"}, {Offset: 31, Pattern: "union select"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AuditSQL(tt.sql); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AuditSQL(%q) = %v, want %v", tt.sql, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditSQLFor(t *testing.T) {
+	// ANSI 中反斜杠不是转义符，'a\' 已闭合，之后的 UNION SELECT 位于结构部分
+	sql := `SELECT 'a\' UNION SELECT 1`
+	if got := AuditSQLFor(sql, DialectANSI); len(got) != 1 || got[0].Pattern != "union select" {
+		t.Errorf("AuditSQLFor(%q, ANSI) = %v, want union select", sql, got)
+	}
+	if got := AuditSQL(sql); got != nil {
+		t.Errorf("AuditSQL(%q) = %v, want nil", sql, got)
+	}
+
+	// PostgreSQL 美元符号引用中的内容不报告
+	if got := AuditSQLFor("SELECT $$1 UNION SELECT 2$$", DialectPostgres); got != nil {
+		t.Errorf("AuditSQLFor(dollar quoted) = %v, want nil", got)
+	}
+
+	// Expand 展开的结果不应有任何发现
+	out, err := Expand("SELECT * FROM t WHERE a = ? AND b = ?", []interface{}{"1' UNION SELECT 2 --", "x'; DROP TABLE t"})
+	if err != nil {
+		t.Fatalf("Expand() error = %v", err)
+	}
+	if got := AuditSQL(out); got != nil {
+		t.Errorf("AuditSQL(%q) = %v, want nil", out, got)
+	}
+}
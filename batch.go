@@ -0,0 +1,65 @@
+package sqlhelper
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// parallelBatchSize 批量处理时元素个数达到该值才分给多个 goroutine 并行处理，
+// 小批量的调度开销大于收益
+const parallelBatchSize = 1024
+
+// ProcessStrings 批量处理字符串，values[i] 使用 types[i] 对应的验证器，结果与逐个调用 ProcessString 相同且保持顺序
+// 元素较多时按 GOMAXPROCS 分段并行处理，此时 OnPatternMatch 等回调会被并发调用
+// values 与 types 长度不一致时返回 error
+func (tap *TypeAwareProcessor) ProcessStrings(values []string, types []ParamType) ([]string, error) {
+	if len(values) != len(types) {
+		return nil, fmt.Errorf("values 有 %d 个元素，types 有 %d 个", len(values), len(types))
+	}
+	results := make([]string, len(values))
+	forEachChunk(len(values), func(from, to int) {
+		for i := from; i < to; i++ {
+			results[i] = tap.ProcessString(values[i], types[i])
+		}
+	})
+	return results, nil
+}
+
+// ProcessStringsInferred 与 ProcessStrings 相同，但每个元素的类型由 inferrer 推断，inferrer 为 nil 时使用全局推断器
+func (tap *TypeAwareProcessor) ProcessStringsInferred(values []string, inferrer *TypeInferrer) []string {
+	if inferrer == nil {
+		_, inferrer = globalPipeline()
+	}
+	results := make([]string, len(values))
+	forEachChunk(len(values), func(from, to int) {
+		for i := from; i < to; i++ {
+			results[i] = tap.ProcessString(values[i], inferrer.InferType(values[i]))
+		}
+	})
+	return results
+}
+
+// forEachChunk 把 [0, n) 分成若干段调用 fn，n 达到 parallelBatchSize 时各段在不同的 goroutine 中并行执行
+// 所有段处理完成后才返回
+func forEachChunk(n int, fn func(from, to int)) {
+	workers := runtime.GOMAXPROCS(0)
+	if n < parallelBatchSize || workers < 2 {
+		fn(0, n)
+		return
+	}
+	size := (n + workers - 1) / workers
+	var wg sync.WaitGroup
+	for from := 0; from < n; from += size {
+		to := from + size
+		if to > n {
+			to = n
+		}
+		wg.Add(1)
+		go func(from, to int) {
+			defer wg.Done()
+			fn(from, to)
+		}(from, to)
+	}
+	wg.Wait()
+}
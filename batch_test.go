@@ -0,0 +1,63 @@
+package sqlhelper
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestProcessStrings(t *testing.T) {
+	processor := NewTypeAwareProcessor()
+
+	values := []string{"user-1", "a;b", "1 UNION SELECT 2", "张三"}
+	types := []ParamType{ParamTypeID, ParamTypeID, ParamTypeGeneric, ParamTypeName}
+	got, err := processor.ProcessStrings(values, types)
+	if err != nil {
+		t.Fatalf("ProcessStrings() error = %v", err)
+	}
+	for i, v := range values {
+		if want := processor.ProcessString(v, types[i]); got[i] != want {
+			t.Errorf("ProcessStrings()[%d] = %q, want %q", i, got[i], want)
+		}
+	}
+
+	if _, err := processor.ProcessStrings(values, types[:2]); err == nil {
+		t.Error("ProcessStrings() 长度不一致时 error = nil")
+	}
+	if got, err := processor.ProcessStrings(nil, nil); err != nil || len(got) != 0 {
+		t.Errorf("ProcessStrings(nil, nil) = %v, %v", got, err)
+	}
+}
+
+func TestProcessStringsParallel(t *testing.T) {
+	processor := NewTypeAwareProcessor()
+	processor.EnableStats()
+
+	// 超过并行阈值的批量，结果仍须与逐个处理一致且保持顺序
+	n := parallelBatchSize*3 + 7
+	values := make([]string, n)
+	types := make([]ParamType, n)
+	for i := range values {
+		values[i] = "id-" + strconv.Itoa(i) + "; DROP TABLE t"
+		types[i] = ParamType(i % 4)
+	}
+	got, err := processor.ProcessStrings(values, types)
+	if err != nil {
+		t.Fatalf("ProcessStrings() error = %v", err)
+	}
+	for i, v := range values {
+		if want := processor.ProcessString(v, types[i]); got[i] != want {
+			t.Fatalf("ProcessStrings()[%d] = %q, want %q", i, got[i], want)
+		}
+	}
+	if stats := processor.Stats(); stats.Total.Processed != int64(2*n) {
+		t.Errorf("Stats().Total.Processed = %d, want %d", stats.Total.Processed, 2*n)
+	}
+
+	inferred := processor.ProcessStringsInferred(values, nil)
+	for i, v := range values {
+		_, want := InspectString(v)
+		if inferred[i] != want {
+			t.Fatalf("ProcessStringsInferred()[%d] = %q, want %q", i, inferred[i], want)
+		}
+	}
+}
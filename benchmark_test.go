@@ -1,6 +1,8 @@
 package sqlhelper
 
 import (
+	"io"
+	"regexp"
 	"strings"
 	"testing"
 )
@@ -184,4 +186,212 @@ func BenchmarkMemoryUsage(b *testing.B) {
 			_ = result // 防止编译器优化
 		}
 	})
-}
\ No newline at end of file
+}
+// BenchmarkWhitespaceCollapse 对比每次编译正则与预编译正则合并空白符的性能
+func BenchmarkWhitespaceCollapse(b *testing.B) {
+	input := "北京  朝阳区\t某某   小区 \n 1期   项目名称"
+
+	b.Run("InlineCompile", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = regexp.MustCompile(`\s+`).ReplaceAllString(input, " ")
+		}
+	})
+
+	b.Run("Precompiled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = whitespaceRe.ReplaceAllString(input, " ")
+		}
+	})
+
+	b.Run("NormalizeInline", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = normalizeInline(input, trace{})
+		}
+	})
+}
+
+// BenchmarkExpandLargeTemplate 对比大模板下 Expand 与 ExpandTo 的内存分配
+func BenchmarkExpandLargeTemplate(b *testing.B) {
+	const rows = 10000
+	sql := strings.Repeat("INSERT INTO t (id, name, note) VALUES (?, ?, ?);\n", rows)
+	vars := make([]interface{}, 0, rows*3)
+	for i := 0; i < rows; i++ {
+		vars = append(vars, i, "项目名称", "描述内容")
+	}
+
+	b.Run("Expand", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = Expand(sql, vars)
+		}
+	})
+
+	b.Run("ExpandTo", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = ExpandTo(io.Discard, sql, vars)
+		}
+	})
+}
+
+// BenchmarkProcessBytes 对比 []byte 直接处理与转换为字符串处理的内存分配
+func BenchmarkProcessBytes(b *testing.B) {
+	processor := NewTypeAwareProcessor()
+	inputs := map[string][]byte{
+		"Clean":  []byte("北京朝阳区某某项目"),
+		"Attack": []byte("'; DROP TABLE users; --"),
+	}
+
+	for name, input := range inputs {
+		b.Run("String_"+name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = []byte(processor.ProcessString(string(input), ParamTypeName))
+			}
+		})
+		b.Run("Bytes_"+name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = processor.ProcessBytes(input, ParamTypeName)
+			}
+		})
+	}
+}
+
+// BenchmarkSafeASCIIFastPath 对比干净 ASCII 输入走快速路径与完整路径的性能
+func BenchmarkSafeASCIIFastPath(b *testing.B) {
+	input := "John Smith user_123"
+	v := NameValidator{}
+
+	b.Run("FastPath", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = v.Validate(input)
+		}
+	})
+
+	b.Run("FullPath", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = limitLength(applyPatterns(v.normalize(input, trace{}), namePatterns, trace{}), v.MaxLength, DefaultNameMaxLength)
+		}
+	})
+}
+
+// BenchmarkProcessorStats 对比开启统计前后 ProcessString 的开销
+func BenchmarkProcessorStats(b *testing.B) {
+	inputs := map[string]string{
+		"Clean":  "John Smith",
+		"Attack": "'; DROP TABLE users; --",
+	}
+
+	for name, input := range inputs {
+		b.Run("Disabled_"+name, func(b *testing.B) {
+			processor := NewTypeAwareProcessor()
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = processor.ProcessString(input, ParamTypeGeneric)
+			}
+		})
+		b.Run("Enabled_"+name, func(b *testing.B) {
+			processor := NewTypeAwareProcessor()
+			processor.EnableStats()
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = processor.ProcessString(input, ParamTypeGeneric)
+			}
+		})
+	}
+}
+
+// BenchmarkExpandPooledBuffer 对比每次新建 strings.Builder 与复用池中缓冲区的内存分配
+func BenchmarkExpandPooledBuffer(b *testing.B) {
+	sql := "SELECT * FROM projects WHERE name = ? AND city = ? AND status = ? AND owner = ?"
+	vars := []interface{}{"北京项目", "北京", 1, "John Smith"}
+
+	b.Run("Builder", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var buf strings.Builder
+			_, _ = literalConfig{}.expandTo(&buf, sql, vars)
+			_ = buf.String()
+		}
+	})
+
+	b.Run("Pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = Expand(sql, vars)
+		}
+	})
+}
+
+// BenchmarkExpandMany 对比 ExpandMany 与逐行调用 Expand 展开同一模板
+func BenchmarkExpandMany(b *testing.B) {
+	sql := "SELECT * FROM orders WHERE user_id = ? AND status = ? AND created_at >= '2024-01-01' AND note <> '?' AND city = ?"
+	rows := make([][]interface{}, 1000)
+	for i := range rows {
+		rows[i] = []interface{}{i, "shipped", "北京"}
+	}
+
+	b.Run("Loop", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, row := range rows {
+				_, _ = Expand(sql, row)
+			}
+		}
+	})
+
+	b.Run("ExpandMany", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = ExpandMany(sql, rows)
+		}
+	})
+}
+
+// BenchmarkApplyPatternsManyHits 测试命中大量危险模式的输入，衡量每次替换后的重新折叠开销
+func BenchmarkApplyPatternsManyHits(b *testing.B) {
+	attack := "x' or '1'='1 union select ascii(substring(concat(a,b),1,1)) -- /* */ # waitfor delay '0:0:5'; drop table t; "
+	inputs := []struct {
+		name  string
+		input string
+	}{
+		{"ASCII", strings.Repeat(attack, 20)},
+		{"Unicode", strings.Repeat("北京"+attack+"İß", 20)},
+	}
+
+	for _, in := range inputs {
+		b.Run(in.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = applyPatterns(in.input, namePatterns, trace{})
+			}
+		})
+	}
+}
+
+// BenchmarkCompiledQuery 对比每次扫描模板的 Expand 和预先编译的 CompiledQuery.Expand
+func BenchmarkCompiledQuery(b *testing.B) {
+	sql := "SELECT id, name, email, created_at FROM users /* 热点查询 */ WHERE tenant_id = ? AND status = ? AND name LIKE ? ORDER BY id LIMIT ?"
+	vars := []interface{}{42, "active", "john%", 20}
+
+	b.Run("Expand", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = Expand(sql, vars)
+		}
+	})
+
+	q, _ := Compile(sql)
+	b.Run("Compiled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = q.Expand(vars)
+		}
+	})
+}
@@ -0,0 +1,156 @@
+package sqlhelper
+
+import (
+	"bytes"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// ProcessBytes 与 ProcessString 相同，但直接处理 []byte，结果与 ProcessString 逐字节一致
+// 内置验证器判断输入无需任何改写时直接返回 value 本身，不产生内存分配；
+// 否则退化为字符串路径并返回新的切片。调用方不应在之后修改 value
+func (tap *TypeAwareProcessor) ProcessBytes(value []byte, paramType ParamType) []byte {
+	validator := tap.GetValidator(paramType)
+	if bv, ok := validator.(byteValidator); ok && bv.unchanged(value) {
+		tap.stats.Load().record(paramType, ValidateInfo{})
+		return value
+	}
+	return []byte(tap.ProcessString(string(value), paramType))
+}
+
+// byteValidator 内置验证器实现的内部接口，用于 ProcessBytes 的快速路径
+type byteValidator interface {
+	// unchanged 报告 value 经过 Validate 后是否保持不变；返回 false 不代表一定会被改写
+	unchanged(value []byte) bool
+}
+
+func (v IDValidator) unchanged(value []byte) bool {
+	if !withinLimit(len(value), v.MaxLength, DefaultIDMaxLength) {
+		return false
+	}
+	for _, c := range value {
+		if !isIDRune(rune(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (v GenericValidator) unchanged(value []byte) bool {
+	if v.BlockStackedQueries && bytes.IndexByte(value, ';') >= 0 {
+		return false
+	}
+	if v.FoldHomoglyphs && bytes.IndexFunc(value, isHomoglyph) >= 0 {
+		return false
+	}
+	if v.PreserveWhitespace {
+		if !norm.NFKC.IsNormal(value) {
+			return false
+		}
+	} else if !isInlineNormal(value) {
+		return false
+	}
+	return withinLimit(len(value), v.MaxLength, DefaultGenericMaxLength) && !containsPatternBytes(value, v.patterns())
+}
+
+func (v NameValidator) unchanged(value []byte) bool {
+	if !withinLimit(len(value), v.MaxLength, DefaultNameMaxLength) || !norm.NFKC.IsNormal(value) {
+		return false
+	}
+	if !v.PreserveWhitespace && !isInlineNormal(value) {
+		return false
+	}
+	if v.FoldHomoglyphs && bytes.IndexFunc(value, isHomoglyph) >= 0 {
+		return false
+	}
+	return !containsPatternBytes(value, v.patterns())
+}
+
+func (v DescriptionValidator) unchanged(value []byte) bool {
+	if !withinLimit(len(value), v.MaxLength, DefaultDescriptionMaxLength) || !norm.NFKC.IsNormal(value) {
+		return false
+	}
+	for _, c := range value {
+		if c == '\r' || (c == '<' && v.StripHTML) {
+			return false
+		}
+	}
+	if v.FoldHomoglyphs && bytes.IndexFunc(value, isHomoglyph) >= 0 {
+		return false
+	}
+	return !containsPatternBytes(value, v.patterns())
+}
+
+// withinLimit 判断长度 n 是否不超过限制；maxLength 为 0 时使用默认值 def，为负数时不限制
+func withinLimit(n, maxLength, def int) bool {
+	if maxLength == 0 {
+		maxLength = def
+	}
+	return maxLength < 0 || n <= maxLength
+}
+
+// isInlineNormal 判断 value 是否已是 normalizeInline 的结果：
+// 已是 NFKC 形式，不含制表、换行等空白，没有连续空格，首尾不是空白
+func isInlineNormal(value []byte) bool {
+	if !norm.NFKC.IsNormal(value) {
+		return false
+	}
+	for i, c := range value {
+		switch c {
+		case '\t', '\n', '\v', '\f', '\r':
+			return false
+		case ' ':
+			if i+1 < len(value) && value[i+1] == ' ' {
+				return false
+			}
+		}
+	}
+	first, _ := utf8.DecodeRune(value)
+	last, _ := utf8.DecodeLastRune(value)
+	return len(value) == 0 || (!unicode.IsSpace(first) && !unicode.IsSpace(last))
+}
+
+// containsPatternBytes 判断 value 中是否大小写不敏感地包含任一危险模式
+// 含有折叠后可能变为 ASCII 的非 ASCII 字符的输入（如 İ、ß、非法 UTF-8）保守地视为包含
+func containsPatternBytes(value []byte, patterns []PatternRule) bool {
+	for i := 0; i < len(value); {
+		if value[i] < utf8.RuneSelf {
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(value[i:])
+		if r == utf8.RuneError || foldsToASCII(r) {
+			return true
+		}
+		i += size
+	}
+	for _, rule := range patterns {
+		if indexFoldASCII(value, rule.Pattern) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// indexFoldASCII 返回小写的 ASCII 模式 pattern 在 value 中按 ASCII 大小写不敏感首次出现的位置，没有时返回 -1
+func indexFoldASCII[T string | []byte](value T, pattern string) int {
+	for i := 0; i+len(pattern) <= len(value); i++ {
+		j := 0
+		for j < len(pattern) && lowerASCII(value[i+j]) == pattern[j] {
+			j++
+		}
+		if j == len(pattern) {
+			return i
+		}
+	}
+	return -1
+}
+
+func lowerASCII(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
@@ -0,0 +1,136 @@
+package sqlhelper
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestProcessBytes(t *testing.T) {
+	inputs := []string{
+		"",
+		"project_123",
+		"北京朝阳区某某项目",
+		"hello world",
+		"  hello   world ",
+		"a\tb",
+		"'; DROP TABLE users; --",
+		"1 UNION SELECT 2",
+		"＇　ｕｎｉｏｎ　ｓｅｌｅｃｔ",
+		"unİon select",
+		"unıon select",
+		"straße",
+		"\xff\xfe'--",
+		"第一行\r\n第二行",
+		"line1\nline2",
+		"name # comment",
+		strings.Repeat("a", 3000),
+		strings.Repeat("长", 200),
+	}
+	types := []ParamType{ParamTypeGeneric, ParamTypeID, ParamTypeName, ParamTypeDescription, ParamTypeJSON, ParamTypeEmail}
+
+	processor := NewTypeAwareProcessor()
+	for _, paramType := range types {
+		for _, input := range inputs {
+			want := processor.ProcessString(input, paramType)
+			if got := processor.ProcessBytes([]byte(input), paramType); string(got) != want {
+				t.Errorf("ProcessBytes(%q, %d) = %q, want %q", input, paramType, got, want)
+			}
+		}
+	}
+
+	// 阻止堆叠查询的通用验证器
+	processor.RegisterValidator(GenericValidator{BlockStackedQueries: true})
+	for _, input := range append(inputs, "a;b", "'a;b'") {
+		want := processor.ProcessString(input, ParamTypeGeneric)
+		if got := processor.ProcessBytes([]byte(input), ParamTypeGeneric); string(got) != want {
+			t.Errorf("ProcessBytes(%q, BlockStackedQueries) = %q, want %q", input, got, want)
+		}
+	}
+
+	// 去掉 HTML 的描述验证器
+	processor.RegisterValidator(DescriptionValidator{StripHTML: true})
+	for _, input := range append(inputs, "<b>x</b>", "1 < 2") {
+		want := processor.ProcessString(input, ParamTypeDescription)
+		if got := processor.ProcessBytes([]byte(input), ParamTypeDescription); string(got) != want {
+			t.Errorf("ProcessBytes(%q, StripHTML) = %q, want %q", input, got, want)
+		}
+	}
+
+	// 保留注释符号的描述验证器
+	processor.RegisterValidator(DescriptionValidator{KeepComments: true})
+	for _, input := range append(inputs, "cost -- estimate", "a /* b */ c") {
+		want := processor.ProcessString(input, ParamTypeDescription)
+		if got := processor.ProcessBytes([]byte(input), ParamTypeDescription); string(got) != want {
+			t.Errorf("ProcessBytes(%q, KeepComments) = %q, want %q", input, got, want)
+		}
+	}
+
+	// 折叠同形字符的通用验证器
+	processor.RegisterValidator(GenericValidator{FoldHomoglyphs: true})
+	for _, input := range append(inputs, "uniоn ѕelect", "Привет") {
+		want := processor.ProcessString(input, ParamTypeGeneric)
+		if got := processor.ProcessBytes([]byte(input), ParamTypeGeneric); string(got) != want {
+			t.Errorf("ProcessBytes(%q, FoldHomoglyphs) = %q, want %q", input, got, want)
+		}
+	}
+
+	// 保留空白的名称验证器
+	processor.RegisterValidator(NameValidator{PreserveWhitespace: true})
+	for _, input := range inputs {
+		want := processor.ProcessString(input, ParamTypeName)
+		if got := processor.ProcessBytes([]byte(input), ParamTypeName); string(got) != want {
+			t.Errorf("ProcessBytes(%q, PreserveWhitespace) = %q, want %q", input, got, want)
+		}
+	}
+
+	// 保留空白的通用验证器
+	processor.RegisterValidator(GenericValidator{PreserveWhitespace: true, BlockStackedQueries: true})
+	for _, input := range inputs {
+		want := processor.ProcessString(input, ParamTypeGeneric)
+		if got := processor.ProcessBytes([]byte(input), ParamTypeGeneric); string(got) != want {
+			t.Errorf("ProcessBytes(%q, PreserveWhitespace) = %q, want %q", input, got, want)
+		}
+	}
+
+	// 保留原值的名称和描述验证器
+	processor.RegisterValidator(NameValidator{KeepOriginal: true})
+	processor.RegisterValidator(DescriptionValidator{KeepOriginal: true})
+	for _, paramType := range []ParamType{ParamTypeName, ParamTypeDescription} {
+		for _, input := range inputs {
+			want := processor.ProcessString(input, paramType)
+			if got := processor.ProcessBytes([]byte(input), paramType); string(got) != want {
+				t.Errorf("ProcessBytes(%q, %d, KeepOriginal) = %q, want %q", input, paramType, got, want)
+			}
+		}
+	}
+}
+
+func TestProcessBytesNoAlloc(t *testing.T) {
+	processor := NewTypeAwareProcessor()
+	tests := []struct {
+		name      string
+		input     []byte
+		paramType ParamType
+	}{
+		{"ID", []byte("project_123"), ParamTypeID},
+		{"名称", []byte("北京朝阳区某某项目"), ParamTypeName},
+		{"通用", []byte("hello world"), ParamTypeGeneric},
+		{"描述", []byte("第一行\n第二行"), ParamTypeDescription},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []byte
+			allocs := testing.AllocsPerRun(100, func() {
+				got = processor.ProcessBytes(tt.input, tt.paramType)
+			})
+			if allocs != 0 {
+				t.Errorf("ProcessBytes(%q) allocs = %v, want 0", tt.input, allocs)
+			}
+			if !bytes.Equal(got, tt.input) {
+				t.Errorf("ProcessBytes(%q) = %q, want unchanged", tt.input, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package sqlhelper
+
+import "strings"
+
+// CompiledQuery Compile 预先解析好的 SQL 模板，可被多个 goroutine 并发使用
+// 占位符位置只在编译时扫描一次，每次 Expand 只需转义参数并拼接，适合反复执行的热点查询
+type CompiledQuery struct {
+	segments     []string // 占位符之间的 SQL 片段，比占位符多一个
+	placeholders []int    // 每个占位符在原 SQL 中的字节偏移
+	size         int      // 所有片段的总长度
+	cfg          literalConfig
+}
+
+// Compile 扫描 sql 中的 ? 占位符，返回可重复使用的 CompiledQuery；占位符的识别规则与 Expand 相同
+// opts 在编译时确定，之后每次 Expand 都使用同样的方言、严格模式等设置；字符串的清理仍使用调用 Expand 时的全局处理器和推断器
+// sql 中有未闭合的引号时返回 *UnbalancedQuoteError
+func Compile(sql string, opts ...ExpandOption) (*CompiledQuery, error) {
+	q := &CompiledQuery{}
+	for _, opt := range opts {
+		opt(&q.cfg)
+	}
+	if err := VerifyBalancedQuotesFor(sql, q.cfg.dialect); err != nil {
+		return nil, err
+	}
+	start := 0
+	for pos := nextPlaceholder(sql, 0, q.cfg.dialect); pos >= 0; pos = nextPlaceholder(sql, start, q.cfg.dialect) {
+		q.segments = append(q.segments, unescapeQuestionMarks(sql[start:pos], q.cfg.dialect))
+		q.placeholders = append(q.placeholders, pos)
+		start = pos + 1
+	}
+	q.segments = append(q.segments, unescapeQuestionMarks(sql[start:], q.cfg.dialect))
+	for _, seg := range q.segments {
+		q.size += len(seg)
+	}
+	return q, nil
+}
+
+// NumPlaceholders 返回模板中占位符的个数
+func (q *CompiledQuery) NumPlaceholders() int {
+	return len(q.placeholders)
+}
+
+// Expand 用 vars 依次替换模板中的占位符，结果与用编译时的选项调用 Expand 相同
+// 参数个数与占位符个数不符时返回 *ExpandError
+func (q *CompiledQuery) Expand(vars []interface{}) (string, error) {
+	if len(vars) != len(q.placeholders) {
+		e := &ExpandError{Placeholders: len(q.placeholders), Args: len(vars), Offset: -1, Msg: "占位符个数 < 参数个数"}
+		if len(vars) < len(q.placeholders) {
+			e.Offset, e.Msg = q.placeholders[len(vars)], "占位符个数 > 参数个数"
+		}
+		return "", e
+	}
+	if err := q.cfg.checkArgCount(vars); err != nil {
+		return "", err
+	}
+	var buf strings.Builder
+	buf.Grow(q.size + 8*len(vars))
+	buf.WriteString(q.segments[0])
+	for i, v := range vars {
+		lit, err := q.cfg.literal(v)
+		if err != nil {
+			return "", err
+		}
+		buf.WriteString(lit)
+		buf.WriteString(q.segments[i+1])
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,86 @@
+package sqlhelper
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		vars []interface{}
+		opts []ExpandOption
+	}{
+		{"无占位符", "SELECT 1", nil, nil},
+		{"多个占位符", "SELECT * FROM t WHERE a = ? AND b = ? AND c IN (?)", []interface{}{1, "it's", []int{1, 2}}, nil},
+		{"引号和注释中的问号", "SELECT '?', `?` -- ?\nFROM t WHERE a = ? /* ? */", []interface{}{"1 UNION SELECT 2"}, nil},
+		{"首尾占位符", "?, ?", []interface{}{true, nil}, nil},
+		{"方言选项", "SELECT ? FROM t WHERE a = ?", []interface{}{`C:\dir`, true}, []ExpandOption{WithDialect(DialectSQLServer)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Compile(tt.sql, tt.opts...)
+			if err != nil {
+				t.Fatalf("Compile(%q) error = %v", tt.sql, err)
+			}
+			if q.NumPlaceholders() != len(tt.vars) {
+				t.Errorf("NumPlaceholders() = %d, want %d", q.NumPlaceholders(), len(tt.vars))
+			}
+			want, err := Expand(tt.sql, tt.vars, tt.opts...)
+			if err != nil {
+				t.Fatalf("Expand(%q) error = %v", tt.sql, err)
+			}
+			// 同一个 CompiledQuery 可重复使用
+			for i := 0; i < 2; i++ {
+				if got, err := q.Expand(tt.vars); err != nil || got != want {
+					t.Errorf("CompiledQuery.Expand() = %q, %v, want %q", got, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	var quoteErr *UnbalancedQuoteError
+	if _, err := Compile("SELECT 'abc WHERE a = ?"); !errors.As(err, &quoteErr) {
+		t.Errorf("Compile(未闭合的引号) error = %v, want *UnbalancedQuoteError", err)
+	}
+
+	q, err := Compile("SELECT ?, ?")
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	tests := []struct {
+		name       string
+		vars       []interface{}
+		wantOffset int
+	}{
+		{"参数不足", []interface{}{1}, 10},
+		{"参数过多", []interface{}{1, 2, 3}, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := q.Expand(tt.vars)
+			var expandErr *ExpandError
+			if !errors.As(err, &expandErr) {
+				t.Fatalf("Expand(%v) error = %v, want *ExpandError", tt.vars, err)
+			}
+			if expandErr.Placeholders != 2 || expandErr.Args != len(tt.vars) || expandErr.Offset != tt.wantOffset {
+				t.Errorf("Expand(%v) error = %+v", tt.vars, *expandErr)
+			}
+			// 与 Expand 返回的错误相同
+			if _, want := Expand("SELECT ?, ?", tt.vars); err.Error() != want.Error() {
+				t.Errorf("Expand(%v) error = %q, want %q", tt.vars, err, want)
+			}
+		})
+	}
+
+	// 编译时的选项在每次展开时生效
+	strict, _ := Compile("SELECT ?", WithStrict())
+	var injection *InjectionError
+	if _, err := strict.Expand([]interface{}{"1 UNION SELECT 2"}); !errors.As(err, &injection) {
+		t.Errorf("Expand(WithStrict) error = %v, want *InjectionError", err)
+	}
+}
@@ -0,0 +1,126 @@
+package sqlhelper
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// Dialect 数据库方言，决定字符串字面量的转义规则
+type Dialect int
+
+const (
+	DialectMySQL     Dialect = iota // MySQL：反斜杠转义特殊字符，默认方言
+	DialectANSI                     // 标准SQL：只双写单引号，反斜杠不是转义字符
+	DialectOracle                   // Oracle：同 DialectANSI
+	DialectSQLServer                // SQL Server：同 DialectANSI
+	DialectPostgres                 // PostgreSQL（standard_conforming_strings=on）：同 DialectANSI，但文本中不允许空字节
+	// DialectMySQLNoBackslashEscapes 开启 NO_BACKSLASH_ESCAPES 的 MySQL：字符串转义同 DialectANSI，
+	// 注释等其余语法同 DialectMySQL
+	DialectMySQLNoBackslashEscapes
+	DialectSQLite // SQLite：同 DialectANSI，换行等控制字符原样保留在引号内
+)
+
+// String 返回方言名称
+func (d Dialect) String() string {
+	switch d {
+	case DialectMySQL:
+		return "MySQL"
+	case DialectANSI:
+		return "ANSI"
+	case DialectOracle:
+		return "Oracle"
+	case DialectSQLServer:
+		return "SQLServer"
+	case DialectPostgres:
+		return "Postgres"
+	case DialectMySQLNoBackslashEscapes:
+		return "MySQLNoBackslashEscapes"
+	case DialectSQLite:
+		return "SQLite"
+	default:
+		return "Dialect(" + strconv.Itoa(int(d)) + ")"
+	}
+}
+
+// QuoteStringFor 按方言转义字符串并加上单引号
+// MySQL 使用反斜杠转义，空字节和 Control-Z 转义为 \0、\Z；其他方言中反斜杠、换行等控制字符都是普通字符，只需双写单引号
+// PostgreSQL 的文本类型不能包含空字节，字面量中出现空字节会直接报错，因此去掉所有空字节
+func QuoteStringFor(s string, d Dialect) string {
+	if d.backslashEscapes() {
+		return quoteString(s)
+	}
+	if d.rejectsNUL() && strings.IndexByte(s, 0) >= 0 {
+		s = strings.ReplaceAll(s, "\x00", "")
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// isMySQL 判断是否为 MySQL 系方言，决定 # 注释和 -- 注释的语法
+func (d Dialect) isMySQL() bool {
+	return d == DialectMySQL || d == DialectMySQLNoBackslashEscapes
+}
+
+// backslashEscapes 判断普通字符串中的反斜杠是否为转义符
+func (d Dialect) backslashEscapes() bool {
+	return d == DialectMySQL
+}
+
+// rejectsNUL 判断方言的文本字面量是否不允许包含空字节
+func (d Dialect) rejectsNUL() bool {
+	return d == DialectPostgres
+}
+
+// quoteIdentifier 按方言给标识符加引号：MySQL 系使用反引号，SQL Server 使用方括号，其他方言使用双引号；
+// 标识符中的结束引号字符双写转义
+func (d Dialect) quoteIdentifier(name string) string {
+	switch {
+	case d.isMySQL():
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	case d == DialectSQLServer:
+		return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+	default:
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	}
+}
+
+// namedPrefix 返回 ExpandNamed 命名占位符的前缀字符：SQL Server 使用 @name，其他方言使用 :name
+func (d Dialect) namedPrefix() byte {
+	if d == DialectSQLServer {
+		return '@'
+	}
+	return ':'
+}
+
+// boolAsInt 判断方言是否没有 TRUE/FALSE 字面量，bool 需要渲染为 1/0
+func (d Dialect) boolAsInt() bool {
+	return d == DialectSQLServer || d == DialectOracle
+}
+
+// binaryLiteralFor 按方言把二进制数据渲染为二进制字符串字面量
+// MySQL 系方言使用 _binary'...'，按字节转义；其他方言使用 HexLiteralFor
+func binaryLiteralFor(b []byte, d Dialect) string {
+	if d.isMySQL() {
+		return "_binary" + QuoteStringFor(string(b), d)
+	}
+	return HexLiteralFor(b, d)
+}
+
+// HexLiteralFor 按方言把二进制数据渲染为十六进制字面量
+// MySQL、ANSI 使用 X'...'；PostgreSQL 使用 bytea 的 '\x...'；SQL Server 使用 0x...；Oracle 使用 HEXTORAW('...')
+func HexLiteralFor(b []byte, d Dialect) string {
+	h := hex.EncodeToString(b)
+	switch d {
+	case DialectPostgres:
+		return `'\x` + h + `'::bytea`
+	case DialectSQLServer:
+		if len(b) == 0 {
+			return "0x"
+		}
+		return "0x" + h
+	case DialectOracle:
+		return "HEXTORAW('" + h + "')"
+	default:
+		return "X'" + h + "'"
+	}
+}
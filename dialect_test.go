@@ -0,0 +1,107 @@
+package sqlhelper
+
+import (
+	"testing"
+)
+
+func TestQuoteStringFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		dialect  Dialect
+		expected string
+	}{
+		{"MySQL - 反斜杠", `a\b`, DialectMySQL, `'a\\b'`},
+		{"MySQL - 换行符", "a\nb", DialectMySQL, `'a\nb'`},
+		{"ANSI - 单引号", "it's", DialectANSI, "'it''s'"},
+		{"ANSI - 反斜杠保持不变", `a\b`, DialectANSI, `'a\b'`},
+		{"ANSI - 反斜杠加单引号", `a\'b`, DialectANSI, `'a\''b'`},
+		{"Oracle - 换行符保持原样", "a\nb", DialectOracle, "'a\nb'"},
+		{"SQLServer - 双引号不转义", `say "hi"`, DialectSQLServer, `'say "hi"'`},
+		{"Postgres - 制表符保持原样", "a\tb", DialectPostgres, "'a\tb'"},
+		{"MySQL无反斜杠转义 - 反斜杠保持不变", `a\b`, DialectMySQLNoBackslashEscapes, `'a\b'`},
+		{"MySQL无反斜杠转义 - 控制字符保持原样", "a\n\t\x00b", DialectMySQLNoBackslashEscapes, "'a\n\t\x00b'"},
+		{"MySQL无反斜杠转义 - 单引号", `it's`, DialectMySQLNoBackslashEscapes, "'it''s'"},
+		{"SQLite - 单引号", `it's`, DialectSQLite, "'it''s'"},
+		{"SQLite - 换行符保持原样", "a\nb", DialectSQLite, "'a\nb'"},
+		{"SQLite - 反斜杠保持不变", `a\n\'b`, DialectSQLite, `'a\n\''b'`},
+		{"MySQL - 空字节和Control-Z转义", "a\x00b\x1a", DialectMySQL, `'a\0b\Z'`},
+		{"Postgres - 去掉空字节", "a\x00b\x00'\x1a", DialectPostgres, "'ab''\x1a'"},
+		{"SQLite - 空字节保持原样", "a\x00b", DialectSQLite, "'a\x00b'"},
+		{"ANSI - 空字节保持原样", "a\x00b", DialectANSI, "'a\x00b'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := QuoteStringFor(tt.input, tt.dialect)
+			if result != tt.expected {
+				t.Errorf("QuoteStringFor(%q, %v) = %q, want %q", tt.input, tt.dialect, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExpandFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		vars    []interface{}
+		dialect Dialect
+		want    string
+	}{
+		{
+			name:    "MySQL与Expand一致",
+			sql:     "SELECT * FROM t WHERE path = ?",
+			vars:    []interface{}{`C:\dir`},
+			dialect: DialectMySQL,
+			want:    `SELECT * FROM t WHERE path = 'C:\\dir'`,
+		},
+		{
+			name:    "SQLServer不转义反斜杠",
+			sql:     "SELECT * FROM t WHERE path = ?",
+			vars:    []interface{}{`C:\dir`},
+			dialect: DialectSQLServer,
+			want:    `SELECT * FROM t WHERE path = 'C:\dir'`,
+		},
+		{
+			name:    "Oracle保留真实换行",
+			sql:     "INSERT INTO t (d) VALUES (?)",
+			vars:    []interface{}{"第一行\n第二行"},
+			dialect: DialectOracle,
+			want:    "INSERT INTO t (d) VALUES ('第一行\n第二行')",
+		},
+		{
+			name:    "MySQL无反斜杠转义模式",
+			sql:     "SELECT * FROM t WHERE path = ? # 注释中的 ?\n AND note = ?",
+			vars:    []interface{}{`C:\dir`, "it's"},
+			dialect: DialectMySQLNoBackslashEscapes,
+			want:    "SELECT * FROM t WHERE path = 'C:\\dir' # 注释中的 ?\n AND note = 'it''s'",
+		},
+		{
+			name:    "SQLite保留真实换行",
+			sql:     "INSERT INTO t (d, p) VALUES (?, ?)",
+			vars:    []interface{}{"第一行\n第二行", `C:\new`},
+			dialect: DialectSQLite,
+			want:    "INSERT INTO t (d, p) VALUES ('第一行\n第二行', 'C:\\new')",
+		},
+		{
+			name:    "切片元素同样按方言转义",
+			sql:     "SELECT * FROM t WHERE name IN (?)",
+			vars:    []interface{}{[]string{"o'neil", `a\b`}},
+			dialect: DialectANSI,
+			want:    `SELECT * FROM t WHERE name IN ('o''neil','a\b')`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandFor(tt.sql, tt.vars, tt.dialect)
+			if err != nil {
+				t.Fatalf("ExpandFor(%q, %v, %v) error = %v", tt.sql, tt.vars, tt.dialect, err)
+			}
+			if got != tt.want {
+				t.Errorf("ExpandFor(%q, %v, %v) = %q, want %q", tt.sql, tt.vars, tt.dialect, got, tt.want)
+			}
+		})
+	}
+}
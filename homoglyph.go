@@ -0,0 +1,44 @@
+package sqlhelper
+
+import "strings"
+
+// homoglyphs 与 ASCII 字母外形相同的常见西里尔字母、希腊字母和国际音标字母，NFKC 不会折叠它们
+var homoglyphs = map[rune]byte{
+	// 西里尔字母
+	'А': 'A', 'а': 'a', 'В': 'B', 'Е': 'E', 'е': 'e', 'К': 'K', 'к': 'k',
+	'М': 'M', 'Н': 'H', 'һ': 'h', 'О': 'O', 'о': 'o', 'Р': 'P', 'р': 'p',
+	'С': 'C', 'с': 'c', 'Т': 'T', 'У': 'Y', 'у': 'y', 'Х': 'X', 'х': 'x',
+	'Ѕ': 'S', 'ѕ': 's', 'І': 'I', 'і': 'i', 'Ј': 'J', 'ј': 'j', 'ԁ': 'd',
+	'ԛ': 'q', 'ԝ': 'w', 'ӏ': 'l', 'Ԍ': 'G',
+	// 希腊字母
+	'Α': 'A', 'α': 'a', 'Β': 'B', 'Ε': 'E', 'Ζ': 'Z', 'Η': 'H', 'Ι': 'I',
+	'ι': 'i', 'Κ': 'K', 'Μ': 'M', 'Ν': 'N', 'ν': 'v', 'Ο': 'O', 'ο': 'o',
+	'Ρ': 'P', 'ρ': 'p', 'Τ': 'T', 'Υ': 'Y', 'υ': 'u', 'Χ': 'X', 'χ': 'x',
+	// 拉丁字母扩展（国际音标）
+	'ɡ': 'g',
+}
+
+// foldHomoglyphs 把 s 中的同形字符替换为对应的 ASCII 字母
+// 替换是有损的：真正的西里尔文、希腊文也会被改写，因此只在验证器显式开启 FoldHomoglyphs 时使用
+func foldHomoglyphs(s string) string {
+	i := strings.IndexFunc(s, isHomoglyph)
+	if i < 0 {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	b.WriteString(s[:i])
+	for _, r := range s[i:] {
+		if c, ok := homoglyphs[r]; ok {
+			b.WriteByte(c)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func isHomoglyph(r rune) bool {
+	_, ok := homoglyphs[r]
+	return ok
+}
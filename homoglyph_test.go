@@ -0,0 +1,66 @@
+package sqlhelper
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFoldHomoglyphs(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator ParamValidator
+		input     string
+		expected  string
+	}{
+		{
+			name:      "西里尔字母混入的联合查询",
+			validator: GenericValidator{FoldHomoglyphs: true},
+			input:     "1 UNІОN SЕLЕCT password FROM users",
+			expected:  "1 union_select password FROM users",
+		},
+		{
+			name:      "小写同形字符",
+			validator: GenericValidator{FoldHomoglyphs: true},
+			input:     "x uniоn ѕelect y",
+			expected:  "x union_select y",
+		},
+		{
+			name:      "希腊字母混入的存储过程",
+			validator: DescriptionValidator{FoldHomoglyphs: true},
+			input:     "exec χp_cmdshell 'dir'",
+			expected:  "exec xp_cmd_shell 'dir'",
+		},
+		{
+			name:      "名称中的布尔注入",
+			validator: NameValidator{FoldHomoglyphs: true},
+			input:     "a оr b",
+			expected:  "a_or_b",
+		},
+		{
+			name:      "中文不受影响",
+			validator: NameValidator{FoldHomoglyphs: true},
+			input:     "北京朝阳区项目",
+			expected:  "北京朝阳区项目",
+		},
+		{
+			name:      "默认不折叠",
+			validator: GenericValidator{},
+			input:     "1 UNІОN SЕLЕCT 2",
+			expected:  "1 UNІОN SЕLЕCT 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.validator.Validate(tt.input); result != tt.expected {
+				t.Errorf("Validate(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+
+	_, err := GenericValidator{FoldHomoglyphs: true}.ValidateStrict("1 UNІОN SЕLЕCT 2")
+	var injErr *InjectionError
+	if !errors.As(err, &injErr) || injErr.Pattern != "union select" {
+		t.Errorf("ValidateStrict() error = %v, want union select", err)
+	}
+}
@@ -0,0 +1,58 @@
+package sqlhelper
+
+// ExpandOption Expand 的单次调用选项，只影响本次调用，不修改全局状态
+type ExpandOption func(*literalConfig)
+
+// WithDialect 按方言 d 转义字符串字面量，同 ExpandFor
+func WithDialect(d Dialect) ExpandOption {
+	return func(c *literalConfig) {
+		c.dialect = d
+	}
+}
+
+// WithStrict 开启严格模式：字符串参数使用验证器的 ValidateStrict，
+// 检测到危险模式时 Expand 返回 *InjectionError 而不是静默改写
+func WithStrict() ExpandOption {
+	return func(c *literalConfig) {
+		c.strict = true
+	}
+}
+
+// WithTimeLayout 使用 layout 格式化 time.Time，代替包级变量 TimeLayout
+func WithTimeLayout(layout string) ExpandOption {
+	return func(c *literalConfig) {
+		c.timeLayout = layout
+	}
+}
+
+// WithBoolAsInt 把 bool 渲染为 1/0 而不是 true/false；DialectSQLServer、DialectOracle 下总是如此
+func WithBoolAsInt() ExpandOption {
+	return func(c *literalConfig) {
+		c.boolAsInt = true
+	}
+}
+
+// WithMaxArgs 限制参数值的总个数，超过 n 时 Expand 不做任何渲染，直接返回 *TooManyArgsError
+// 切片按元素个数计数（[]byte 等二进制数据计为 1 个），用于防止意外或恶意构造的超大 IN 列表耗尽内存
+// n <= 0 表示不限制，与不传该选项相同
+func WithMaxArgs(n int) ExpandOption {
+	return func(c *literalConfig) {
+		c.maxArgs = n
+	}
+}
+
+// WithFixedPointFloats 把 float32、float64 渲染为不带指数的定点格式，如 1e+21 渲染为 1000000000000000000000、
+// 1e-07 渲染为 0.0000001，避免写入 DECIMAL 列时出现意外的科学计数法；仍使用能精确还原该浮点数的最短位数
+// 需要十进制精确值（如金额）时应使用 Numeric，而不是 float64
+func WithFixedPointFloats() ExpandOption {
+	return func(c *literalConfig) {
+		c.fixedFloat = true
+	}
+}
+
+// WithNaNAsNull 把 NaN、+Inf、-Inf 渲染为 NULL；不设置时这些值返回 ErrNonFiniteFloat，避免生成非法的 SQL
+func WithNaNAsNull() ExpandOption {
+	return func(c *literalConfig) {
+		c.nanAsNull = true
+	}
+}
@@ -0,0 +1,233 @@
+package sqlhelper
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestExpandOptions(t *testing.T) {
+	ts := time.Date(2024, 3, 5, 6, 7, 8, 0, time.UTC)
+	tests := []struct {
+		name string
+		sql  string
+		vars []interface{}
+		opts []ExpandOption
+		want string
+	}{
+		{
+			name: "不传选项与原行为一致",
+			sql:  "SELECT * FROM t WHERE a = ? AND b = ?",
+			vars: []interface{}{`C:\dir`, ts},
+			want: `SELECT * FROM t WHERE a = 'C:\\dir' AND b = '2024-03-05 06:07:08'`,
+		},
+		{
+			name: "WithDialect",
+			sql:  "SELECT * FROM t WHERE a = ?",
+			vars: []interface{}{`C:\dir`},
+			opts: []ExpandOption{WithDialect(DialectANSI)},
+			want: `SELECT * FROM t WHERE a = 'C:\dir'`,
+		},
+		{
+			name: "WithTimeLayout",
+			sql:  "SELECT * FROM t WHERE d = ?",
+			vars: []interface{}{ts},
+			opts: []ExpandOption{WithTimeLayout("2006-01-02")},
+			want: "SELECT * FROM t WHERE d = '2024-03-05'",
+		},
+		{
+			name: "WithStrict 不影响安全输入",
+			sql:  "SELECT * FROM t WHERE a = ? AND b IN (?)",
+			vars: []interface{}{"张三", TypedList{Type: ParamTypeID, Values: []string{"u-1"}}},
+			opts: []ExpandOption{WithStrict()},
+			want: "SELECT * FROM t WHERE a = '张三' AND b IN ('u-1')",
+		},
+		{
+			name: "多个选项组合",
+			sql:  "SELECT * FROM t WHERE a = ? AND d = ?",
+			vars: []interface{}{"it's", ts},
+			opts: []ExpandOption{WithDialect(DialectPostgres), WithTimeLayout("15:04"), WithStrict()},
+			want: "SELECT * FROM t WHERE a = 'it''s' AND d = '06:07'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Expand(tt.sql, tt.vars, tt.opts...)
+			if err != nil {
+				t.Fatalf("Expand() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// 选项不改变全局状态
+	if TimeLayout != DefaultTimeLayout {
+		t.Errorf("TimeLayout = %q, want unchanged", TimeLayout)
+	}
+}
+
+func TestExpandWithStrict(t *testing.T) {
+	attacks := []interface{}{
+		"1 UNION SELECT password FROM users",
+		[]string{"ok", "x'; DROP TABLE t"},
+		TypedList{Type: ParamTypeName, Values: []string{"a' OR '1'='1"}},
+	}
+
+	for _, v := range attacks {
+		_, err := Expand("SELECT * FROM t WHERE a IN (?)", []interface{}{v}, WithStrict())
+		var injection *InjectionError
+		if !errors.As(err, &injection) {
+			t.Errorf("Expand(%v, WithStrict()) error = %v, want *InjectionError", v, err)
+		}
+		// 不开启严格模式时照常改写
+		if _, err := Expand("SELECT * FROM t WHERE a IN (?)", []interface{}{v}); err != nil {
+			t.Errorf("Expand(%v) error = %v", v, err)
+		}
+	}
+
+	// Sanitizer 同样接受选项
+	s := NewSanitizer(DialectMySQL)
+	if _, err := s.Expand("SELECT ?", []interface{}{"1 UNION SELECT 2"}, WithStrict()); err == nil {
+		t.Error("Sanitizer.Expand(WithStrict()) error = nil")
+	}
+}
+
+func TestBoolRendering(t *testing.T) {
+	tests := []struct {
+		name      string
+		dialect   Dialect
+		boolAsInt bool
+		wantTrue  string
+		wantFalse string
+	}{
+		{"MySQL默认", DialectMySQL, false, "true", "false"},
+		{"MySQL整数", DialectMySQL, true, "1", "0"},
+		{"Postgres默认", DialectPostgres, false, "true", "false"},
+		{"Postgres整数", DialectPostgres, true, "1", "0"},
+		{"SQLite整数", DialectSQLite, true, "1", "0"},
+		{"SQLServer总是整数", DialectSQLServer, false, "1", "0"},
+		{"Oracle总是整数", DialectOracle, false, "1", "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []ExpandOption{WithDialect(tt.dialect)}
+			if tt.boolAsInt {
+				opts = append(opts, WithBoolAsInt())
+			}
+			got, err := Expand("SELECT ?, ?, ?", []interface{}{true, false, sql.NullBool{Bool: true, Valid: true}}, opts...)
+			if err != nil {
+				t.Fatalf("Expand() error = %v", err)
+			}
+			if want := "SELECT " + tt.wantTrue + ", " + tt.wantFalse + ", " + tt.wantTrue; got != want {
+				t.Errorf("Expand() = %q, want %q", got, want)
+			}
+
+			s := &Sanitizer{Dialect: tt.dialect, BoolAsInt: tt.boolAsInt}
+			if got, _ := s.Literal(false); got != tt.wantFalse {
+				t.Errorf("Sanitizer.Literal(false) = %q, want %q", got, tt.wantFalse)
+			}
+		})
+	}
+}
+
+func TestExpandWithMaxArgs(t *testing.T) {
+	ids := make([]int, 1000)
+	tests := []struct {
+		name      string
+		sql       string
+		vars      []interface{}
+		max       int
+		wantCount int // 0 表示不应出错
+	}{
+		{"未超过上限", "SELECT * FROM t WHERE a = ? AND b = ?", []interface{}{1, 2}, 2, 0},
+		{"超过上限", "SELECT ?, ?, ?", []interface{}{1, 2, 3}, 2, 3},
+		{"切片按元素计数", "SELECT * FROM t WHERE id IN (?)", []interface{}{ids}, 100, 1000},
+		{"TypedList按元素计数", "SELECT * FROM t WHERE id IN (?)", []interface{}{TypedList{Type: ParamTypeID, Values: []string{"a", "b", "c"}}}, 2, 3},
+		{"字节切片计为一个", "SELECT ?", []interface{}{make([]byte, 100)}, 1, 0},
+		{"不限制", "SELECT * FROM t WHERE id IN (?)", []interface{}{ids}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Expand(tt.sql, tt.vars, WithMaxArgs(tt.max))
+			if tt.wantCount == 0 {
+				if err != nil {
+					t.Errorf("Expand(WithMaxArgs(%d)) error = %v", tt.max, err)
+				}
+				return
+			}
+			var tooMany *TooManyArgsError
+			if !errors.As(err, &tooMany) {
+				t.Fatalf("Expand(WithMaxArgs(%d)) error = %v, want *TooManyArgsError", tt.max, err)
+			}
+			if tooMany.Limit != tt.max || tooMany.Count != tt.wantCount {
+				t.Errorf("TooManyArgsError = %+v, want Limit %d Count %d", *tooMany, tt.max, tt.wantCount)
+			}
+		})
+	}
+
+	// ExpandNamed 只计算被引用的参数，同一个名字只计一次
+	args := map[string]interface{}{"ids": []int{1, 2, 3}, "unused": ids}
+	if _, err := ExpandNamed("SELECT * FROM t WHERE a IN (:ids) OR b IN (:ids)", args, WithMaxArgs(3)); err != nil {
+		t.Errorf("ExpandNamed(WithMaxArgs(3)) error = %v", err)
+	}
+	var tooMany *TooManyArgsError
+	if _, err := ExpandNamed("SELECT * FROM t WHERE a IN (:ids)", args, WithMaxArgs(2)); !errors.As(err, &tooMany) {
+		t.Errorf("ExpandNamed(WithMaxArgs(2)) error = %v, want *TooManyArgsError", err)
+	}
+}
+
+func TestFloatRendering(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     interface{}
+		wantDflt  string // 默认的 'g' 格式
+		wantFixed string // WithFixedPointFloats
+	}{
+		{"普通小数", 1.5, "1.5", "1.5"},
+		{"整数值", 100.0, "100", "100"},
+		{"负数", -0.25, "-0.25", "-0.25"},
+		{"很大的值", 1e21, "1e+21", "1000000000000000000000"},
+		{"很小的值", 1.5e-7, "1.5e-07", "0.00000015"},
+		{"float32", float32(0.5), "0.5", "0.5"},
+		{"float32 很大的值", float32(1e21), "1.0000000200408773e+21", "1000000020040877300000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Expand("SELECT ?", []interface{}{tt.input})
+			if err != nil || got != "SELECT "+tt.wantDflt {
+				t.Errorf("Expand(%v) = %q, %v, want %q", tt.input, got, err, "SELECT "+tt.wantDflt)
+			}
+			got, err = Expand("SELECT ?", []interface{}{tt.input}, WithFixedPointFloats())
+			if err != nil || got != "SELECT "+tt.wantFixed {
+				t.Errorf("Expand(%v, WithFixedPointFloats()) = %q, %v, want %q", tt.input, got, err, "SELECT "+tt.wantFixed)
+			}
+		})
+	}
+}
+
+func TestNonFiniteFloat(t *testing.T) {
+	inputs := []interface{}{
+		math.NaN(), math.Inf(1), math.Inf(-1), float32(math.Inf(1)), sql.NullFloat64{Float64: math.NaN(), Valid: true},
+		new(big.Float).SetInf(false), new(big.Float).SetInf(true),
+	}
+	for _, v := range inputs {
+		if got, err := Expand("SELECT ?", []interface{}{v}); !errors.Is(err, ErrNonFiniteFloat) {
+			t.Errorf("Expand(%v) = %q, %v, want ErrNonFiniteFloat", v, got, err)
+		}
+		if got, err := Expand("SELECT ?", []interface{}{v}, WithNaNAsNull()); err != nil || got != "SELECT NULL" {
+			t.Errorf("Expand(%v, WithNaNAsNull()) = %q, %v, want %q", v, got, err, "SELECT NULL")
+		}
+	}
+	if got, err := Expand("SELECT ?", []interface{}{1.5}, WithNaNAsNull()); err != nil || got != "SELECT 1.5" {
+		t.Errorf("Expand(1.5, WithNaNAsNull()) = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,656 @@
+package sqlhelper
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExpandPg 把带 $1、$2 … 占位符的 PostgreSQL 风格 SQL 展开成可直接执行的纯文本 SQL
+// 同一个 $N 可以出现多次，每次都替换为同一个参数的字面量
+// 引号字符串、美元符号引用($$...$$)和注释中的 $ 原样保留，不会被当作占位符
+// 如果 $N 超出参数范围，或有参数从未被引用，返回 error
+// 字符串按 DialectPostgres 转义
+func ExpandPg(sql string, vars []interface{}) (string, error) {
+	var (
+		cfg  = literalConfig{dialect: DialectPostgres}
+		buf  strings.Builder
+		lits = make([]string, len(vars)) // 每个参数只转义一次
+		used = make([]bool, len(vars))
+		last int
+	)
+	for i := 0; i < len(sql); {
+		if j := skipNonCode(sql, i, DialectPostgres); j > i {
+			i = j
+			continue
+		}
+		switch sql[i] {
+		case '$':
+			j := i + 1
+			for j < len(sql) && isDigit(sql[j]) {
+				j++
+			}
+			if j == i+1 {
+				// 不是 $N，可能是 $tag$ 美元符号引用
+				i = skipDollarQuoted(sql, i)
+				continue
+			}
+			if i > 0 && isIdentByte(sql[i-1]) {
+				// 标识符中间的 $，如 a$1
+				i = j
+				continue
+			}
+			n, err := strconv.Atoi(sql[i+1 : j])
+			if err != nil || n < 1 || n > len(vars) {
+				return "", fmt.Errorf("占位符 %s 超出参数范围 $1..$%d", sql[i:j], len(vars))
+			}
+			if !used[n-1] {
+				lit, err := cfg.literal(vars[n-1])
+				if err != nil {
+					return "", err
+				}
+				lits[n-1] = lit
+				used[n-1] = true
+			}
+			buf.WriteString(sql[last:i])
+			buf.WriteString(lits[n-1])
+			last = j
+			i = j
+		default:
+			i++
+		}
+	}
+	for n, ok := range used {
+		if !ok {
+			return "", fmt.Errorf("参数 $%d 未被 SQL 引用", n+1)
+		}
+	}
+	buf.WriteString(sql[last:])
+	return buf.String(), nil
+}
+
+// ExpandNamed 把带 :name 命名占位符的 SQL 展开成可直接执行的纯文本 SQL
+// name 由字母、数字、下划线组成且不能以数字开头，同一个名字可以出现多次
+// 引号字符串、注释中的冒号（如 '12:30:00'）和 PostgreSQL 的 :: 类型转换不会被当作占位符
+// 如果 SQL 引用了 args 中不存在的名字，返回 error；args 中多余的键可用 UnusedNamedArgs 检查
+// opts 可指定方言等选项：WithDialect(DialectSQLServer) 时占位符改为 SQL Server 风格的 @name，
+// @@ROWCOUNT 这类系统变量和引号字符串中的 @（如邮箱地址）不会被当作占位符
+func ExpandNamed(sql string, args map[string]interface{}, opts ...ExpandOption) (string, error) {
+	var (
+		cfg  literalConfig
+		buf  strings.Builder
+		lits = make(map[string]string, len(args)) // 每个参数只转义一次
+		last int
+	)
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if err := cfg.checkNamedArgCount(sql, args); err != nil {
+		return "", err
+	}
+	err := forEachNamed(sql, cfg.dialect, func(start, end int) error {
+		name := sql[start+1 : end]
+		lit, ok := lits[name]
+		if !ok {
+			v, exists := args[name]
+			if !exists {
+				return fmt.Errorf("命名参数 %s 不存在", sql[start:end])
+			}
+			var err error
+			if lit, err = cfg.literal(v); err != nil {
+				return err
+			}
+			lits[name] = lit
+		}
+		buf.WriteString(sql[last:start])
+		buf.WriteString(lit)
+		last = end
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	buf.WriteString(sql[last:])
+	return buf.String(), nil
+}
+
+// checkNamedArgCount 检查 SQL 引用的命名参数值的总个数是否超过 maxArgs，同一个名字只计一次
+func (c literalConfig) checkNamedArgCount(sql string, args map[string]interface{}) error {
+	if c.maxArgs <= 0 {
+		return nil
+	}
+	var vars []interface{}
+	seen := make(map[string]bool)
+	_ = forEachNamed(sql, c.dialect, func(start, end int) error {
+		name := sql[start+1 : end]
+		if v, exists := args[name]; exists && !seen[name] {
+			seen[name] = true
+			vars = append(vars, v)
+		}
+		return nil
+	})
+	return c.checkArgCount(vars)
+}
+
+// ExpandNamedArgs 展开同时含有 ? 位置占位符和 :name、@name 命名占位符的 SQL，args 可以混合传入普通值和 sql.Named 创建的 sql.NamedArg，
+// 便于直接复用按 database/sql 习惯组织的参数。? 按顺序使用普通值，命名占位符按名字（区分大小写）使用 sql.NamedArg，同一个名字可以出现多次
+// 引号字符串、注释中的内容，:: 类型转换和 @@ 开头的系统变量不会被当作占位符，?? 与 Expand 相同输出为字面量 ?；值按 Expand 的规则转义
+// 没有同名 sql.NamedArg 的 @name 视为 MySQL 用户变量原样保留（SET @x = 1）；
+// :name 没有对应的 sql.NamedArg、sql.NamedArg 的名字为空或重复时返回 error；普通值与 ? 个数不符时返回 *ExpandError。
+// 未被引用的 sql.NamedArg 不报错，与 ExpandNamed 相同
+func ExpandNamedArgs(sql string, args ...interface{}) (string, error) {
+	positional, named, err := splitNamedArgs(args)
+	if err != nil {
+		return "", err
+	}
+	var (
+		cfg   = literalConfig{}
+		buf   strings.Builder
+		lits  = make(map[string]string, len(named)) // 每个命名参数只转义一次
+		argI  int
+		last  int
+		write = func(start, end int, lit string) {
+			buf.WriteString(sql[last:start])
+			buf.WriteString(lit)
+			last = end
+		}
+	)
+	for i := 0; i < len(sql); {
+		if j := skipNonCode(sql, i, DialectMySQL); j > i {
+			i = j
+			continue
+		}
+		switch c := sql[i]; c {
+		case '?':
+			if i+1 < len(sql) && sql[i+1] == '?' {
+				write(i, i+2, "?") // ?? 转义的字面量 ?
+				i += 2
+				continue
+			}
+			if argI >= len(positional) {
+				return "", newExpandError("占位符个数 > 参数个数", sql, DialectMySQL, len(positional), i)
+			}
+			lit, err := cfg.literal(positional[argI])
+			if err != nil {
+				return "", err
+			}
+			write(i, i+1, lit)
+			argI++
+			i++
+		case ':', '@':
+			if i+1 < len(sql) && sql[i+1] == c {
+				i += 2 // :: 类型转换、@@ 系统变量
+				continue
+			}
+			j := i + 1
+			for j < len(sql) && isIdentByte(sql[j]) {
+				j++
+			}
+			if j == i+1 || isDigit(sql[i+1]) {
+				i = j
+				continue
+			}
+			name := sql[i+1 : j]
+			lit, ok := lits[name]
+			if !ok {
+				v, exists := named[name]
+				if !exists && c == '@' {
+					i = j // MySQL 的 @x 用户变量，原样保留
+					continue
+				}
+				if !exists {
+					return "", fmt.Errorf("命名参数 %s 没有对应的 sql.NamedArg", sql[i:j])
+				}
+				if lit, err = cfg.literal(v); err != nil {
+					return "", err
+				}
+				lits[name] = lit
+			}
+			write(i, j, lit)
+			i = j
+		default:
+			i++
+		}
+	}
+	if argI != len(positional) {
+		return "", newExpandError("占位符个数 < 参数个数", sql, DialectMySQL, len(positional), -1)
+	}
+	buf.WriteString(sql[last:])
+	return buf.String(), nil
+}
+
+// splitNamedArgs 把 args 分为按顺序使用的普通值和按名字使用的 sql.NamedArg
+func splitNamedArgs(args []interface{}) ([]interface{}, map[string]interface{}, error) {
+	var (
+		positional []interface{}
+		named      = make(map[string]interface{})
+	)
+	for _, arg := range args {
+		na, ok := arg.(sql.NamedArg)
+		if !ok {
+			positional = append(positional, arg)
+			continue
+		}
+		if na.Name == "" {
+			return nil, nil, fmt.Errorf("sql.NamedArg 的名字不能为空")
+		}
+		if _, dup := named[na.Name]; dup {
+			return nil, nil, fmt.Errorf("sql.NamedArg %q 重复", na.Name)
+		}
+		named[na.Name] = na.Value
+	}
+	return positional, named, nil
+}
+
+// ExpandPrepared 把 :name 命名占位符转换为 ? 占位符，返回可交给 database/sql 预编译执行的 SQL 和按顺序展开的参数
+// 值本身不会内联到 SQL 中。切片参数展开为与元素个数相同的 ?（用于 IN 子句），空切片渲染为 NULL；
+// []byte 和实现了 driver.Valuer 的切片类型作为单个参数。命名规则和错误与 ExpandNamed 相同
+// 本包的 TypedList 同样展开为多个 ?，每个元素先用 Type 对应的验证器清理；HexBytes、BinaryBytes、Numeric、JSONValue、
+// time.Duration、*big.Int、*big.Float 转换为驱动可以绑定的 []byte、string、int64（见 preparedArg），Numeric 不合法时返回 error
+// 输出中的 ? 都会被驱动当作占位符，因此 sql 的字符串和注释之外已有 ?（包括 ?? 转义）时返回 error，
+// 否则返回的参数无法与占位符一一对应
+func ExpandPrepared(sql string, args map[string]interface{}) (string, []interface{}, error) {
+	for i := 0; i < len(sql); {
+		if j := skipNonCode(sql, i, DialectMySQL); j > i {
+			i = j
+			continue
+		}
+		if sql[i] == '?' {
+			return "", nil, fmt.Errorf("位置 %d: ExpandPrepared 不支持 ? 位置占位符与命名占位符混用", i)
+		}
+		i++
+	}
+	var (
+		buf  strings.Builder
+		flat []interface{}
+		last int
+	)
+	// writeList 写入 n 个以逗号分隔的 ?，item 返回第 i 个参数；n 为 0 时写入 NULL
+	writeList := func(n int, item func(i int) (interface{}, error)) error {
+		if n == 0 {
+			buf.WriteString("NULL")
+			return nil
+		}
+		for i := 0; i < n; i++ {
+			arg, err := item(i)
+			if err != nil {
+				return err
+			}
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteByte('?')
+			flat = append(flat, arg)
+		}
+		return nil
+	}
+	err := forEachNamed(sql, DialectMySQL, func(start, end int) error {
+		name := sql[start+1 : end]
+		v, exists := args[name]
+		if !exists {
+			return fmt.Errorf("命名参数 :%s 不存在", name)
+		}
+		buf.WriteString(sql[last:start])
+		last = end
+		var err error
+		if list, ok := v.(TypedList); ok {
+			processor, _ := globalPipeline()
+			err = writeList(len(list.Values), func(i int) (interface{}, error) {
+				return processor.ProcessString(list.Values[i], list.Type), nil
+			})
+		} else if rv := reflect.ValueOf(v); isExpandableSlice(v, rv) {
+			err = writeList(rv.Len(), func(i int) (interface{}, error) {
+				return preparedArg(rv.Index(i).Interface())
+			})
+		} else {
+			err = writeList(1, func(int) (interface{}, error) { return preparedArg(v) })
+		}
+		if err != nil {
+			return fmt.Errorf("命名参数 :%s: %w", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	buf.WriteString(sql[last:])
+	return buf.String(), flat, nil
+}
+
+// preparedArg 把只有 literal 认识的本包类型和 literal 特殊渲染的类型转换为 database/sql 驱动可以绑定的值，
+// 转换结果与 Expand 内联的字面量含义相同；其他值原样返回，由驱动自行转换
+func preparedArg(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case HexBytes:
+		return []byte(val), nil
+	case BinaryBytes:
+		return []byte(val), nil
+	case Numeric:
+		return numericLiteral(val)
+	case JSONValue:
+		if val == nil {
+			return nil, nil
+		}
+		b, err := json.Marshal(map[string]interface{}(val))
+		if err != nil {
+			return nil, fmt.Errorf("JSONValue: %w", err)
+		}
+		return string(b), nil
+	case time.Duration:
+		return durationCount(val, DurationUnit), nil
+	case *big.Int:
+		if val == nil {
+			return nil, nil
+		}
+		return val.String(), nil
+	case *big.Float:
+		if val == nil {
+			return nil, nil
+		}
+		if val.IsInf() {
+			return nil, fmt.Errorf("%w: %s", ErrNonFiniteFloat, val.String())
+		}
+		return val.Text('f', -1), nil
+	}
+	return v, nil
+}
+
+// isExpandableSlice 判断 v 是否为需要展开成多个参数的切片
+func isExpandableSlice(v interface{}, rv reflect.Value) bool {
+	if _, ok := v.(driver.Valuer); ok {
+		return false
+	}
+	return rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8
+}
+
+// UnusedNamedArgs 返回 args 中未被 SQL 引用的键（按字典序），用于在 ExpandNamed 前后发出告警
+// opts 应与调用 ExpandNamed 时相同，WithDialect(DialectSQLServer) 时按 @name 识别占位符
+func UnusedNamedArgs(sql string, args map[string]interface{}, opts ...ExpandOption) []string {
+	var cfg literalConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	used := make(map[string]bool, len(args))
+	_ = forEachNamed(sql, cfg.dialect, func(start, end int) error {
+		used[sql[start+1:end]] = true
+		return nil
+	})
+	var unused []string
+	for name := range args {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+	sort.Strings(unused)
+	return unused
+}
+
+// forEachNamed 按出现顺序对 SQL 中每个方言 d 风格的命名占位符（见 Dialect.namedPrefix）调用 fn，
+// 按 d 的规则跳过字符串、引号标识符和注释；连续两个前缀字符（:: 类型转换、@@ 系统变量）不是占位符
+// start 指向前缀字符，end 指向名字之后的位置；fn 返回 error 时立即停止并返回该 error
+func forEachNamed(sql string, d Dialect, fn func(start, end int) error) error {
+	prefix := d.namedPrefix()
+	for i := 0; i < len(sql); {
+		if j := skipNonCode(sql, i, d); j > i {
+			i = j
+			continue
+		}
+		switch c := sql[i]; {
+		case c == prefix:
+			if i+1 < len(sql) && sql[i+1] == prefix {
+				i += 2 // :: 类型转换、@@ 系统变量
+				continue
+			}
+			j := i + 1
+			for j < len(sql) && isIdentByte(sql[j]) {
+				j++
+			}
+			if j == i+1 || isDigit(sql[i+1]) {
+				i = j
+				continue
+			}
+			if err := fn(i, j); err != nil {
+				return err
+			}
+			i = j
+		default:
+			i++
+		}
+	}
+	return nil
+}
+
+// UnbalancedQuoteError VerifyBalancedQuotes 发现未闭合的引号时返回的错误
+type UnbalancedQuoteError struct {
+	Offset int  // 未闭合的开始引号在 SQL 中的字节偏移
+	Quote  byte // 引号字符：' " 或 `
+}
+
+func (e *UnbalancedQuoteError) Error() string {
+	return fmt.Sprintf("位置 %d 的引号 %c 未闭合", e.Offset, e.Quote)
+}
+
+// VerifyBalancedQuotes 检查 Expand 展开后的 SQL 中所有引号是否都正确配对和转义，
+// 发现未闭合的引号时返回 *UnbalancedQuoteError，其中包含第一个未闭合引号的位置
+// 用作展开后的兜底检查：转义一旦出现回归导致字面量没有闭合，可以在执行前发现。字符串按 DialectMySQL 的规则扫描
+func VerifyBalancedQuotes(sql string) error {
+	return VerifyBalancedQuotesFor(sql, DialectMySQL)
+}
+
+// VerifyBalancedQuotesFor 与 VerifyBalancedQuotes 相同，但按方言 d 的转义和注释规则扫描，用于检查 ExpandFor 的结果
+// 注释中的引号不参与配对；PostgreSQL 方言下美元符号引用（$$...$$）中的内容同样跳过
+func VerifyBalancedQuotesFor(sql string, d Dialect) error {
+	for i := 0; i < len(sql); {
+		switch c := sql[i]; c {
+		case '\'', '"', '`':
+			end := quotedEnd(sql, i, quoteEscapes(sql, i, d))
+			if end < 0 {
+				return &UnbalancedQuoteError{Offset: i, Quote: c}
+			}
+			i = end
+			continue
+		case '$':
+			if d == DialectPostgres {
+				i = skipDollarQuoted(sql, i)
+				continue
+			}
+		case '-', '#', '/':
+			if j := skipNonCode(sql, i, d); j > i {
+				i = j
+				continue
+			}
+		}
+		i++
+	}
+	return nil
+}
+
+// nextPlaceholder 返回 from 之后第一个不在字符串、引号标识符或注释中的 ? 的位置，没有时返回 -1
+// 连续的 ?? 是转义的字面量 ?，不是占位符，输出时由 unescapeQuestionMarks 还原为单个 ?
+// 按字节扫描，全角 ？ 的 UTF-8 编码中不含 '?'，不会被误认为占位符
+func nextPlaceholder(sql string, from int, d Dialect) int {
+	for i := from; i < len(sql); {
+		switch sql[i] {
+		case '?':
+			if i+1 < len(sql) && sql[i+1] == '?' {
+				i += 2
+				continue
+			}
+			return i
+		case '\'', '"', '`', '-', '#', '/':
+			if j := skipNonCode(sql, i, d); j > i {
+				i = j
+				continue
+			}
+		}
+		i++
+	}
+	return -1
+}
+
+// unescapeQuestionMarks 把 s 中不在字符串、引号标识符或注释中的 ?? 替换为单个 ?
+// s 应是 nextPlaceholder 切分出的占位符之间的片段，开头不在字符串或注释内
+func unescapeQuestionMarks(s string, d Dialect) string {
+	if !strings.Contains(s, "??") {
+		return s
+	}
+	var buf strings.Builder
+	buf.Grow(len(s))
+	last := 0
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '?':
+			if i+1 < len(s) && s[i+1] == '?' {
+				buf.WriteString(s[last : i+1])
+				i += 2
+				last = i
+				continue
+			}
+		case '\'', '"', '`', '-', '#', '/':
+			if j := skipNonCode(s, i, d); j > i {
+				i = j
+				continue
+			}
+		}
+		i++
+	}
+	buf.WriteString(s[last:])
+	return buf.String()
+}
+
+// CountPlaceholders 返回 Expand 会替换的 ? 占位符个数，与 Expand 使用同一个扫描器，
+// 字符串、引号标识符和注释中的 ? 不计入，可用于在参数就绪前检查 len(args)
+func CountPlaceholders(sql string) int {
+	return countPlaceholders(sql, DialectMySQL)
+}
+
+// countPlaceholders 统计 SQL 中不在字符串、引号标识符或注释中的 ? 个数
+func countPlaceholders(sql string, d Dialect) int {
+	n := 0
+	for i := nextPlaceholder(sql, 0, d); i >= 0; i = nextPlaceholder(sql, i+1, d) {
+		n++
+	}
+	return n
+}
+
+// skipNonCode 如果从 sql[i] 开始的是字符串、引号标识符或注释，返回其结束之后的位置，否则返回 i
+// 引号内两个连续的引号视为转义；MySQL 方言下反斜杠也是转义符，并支持 # 单行注释；
+// PostgreSQL 方言下只有 E'...' 字符串支持反斜杠转义。未闭合时返回 len(sql)
+func skipNonCode(sql string, i int, d Dialect) int {
+	switch c := sql[i]; c {
+	case '\'', '"', '`':
+		return skipQuoted(sql, i, quoteEscapes(sql, i, d))
+	case '-':
+		// MySQL 要求 -- 之后紧跟空白才是注释
+		if strings.HasPrefix(sql[i:], "--") &&
+			(!d.isMySQL() || i+2 == len(sql) || isSpaceByte(sql[i+2])) {
+			return skipLine(sql, i)
+		}
+	case '#':
+		if d.isMySQL() {
+			return skipLine(sql, i)
+		}
+	case '/':
+		if strings.HasPrefix(sql[i:], "/*") {
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				return len(sql)
+			}
+			return i + 2 + end + 2
+		}
+	}
+	return i
+}
+
+// quoteEscapes 判断从 sql[i] 开始的字符串或引号标识符中反斜杠是否为转义符
+func quoteEscapes(sql string, i int, d Dialect) bool {
+	switch sql[i] {
+	case '\'':
+		return d.backslashEscapes() || (d == DialectPostgres && isEscapeStringPrefix(sql, i))
+	case '"':
+		return d.backslashEscapes()
+	}
+	return false
+}
+
+// skipQuoted 跳过从 sql[i] 开始、以 sql[i] 作为引号的字符串或标识符，返回结束引号之后的位置，未闭合时返回 len(sql)
+func skipQuoted(sql string, i int, backslash bool) int {
+	if end := quotedEnd(sql, i, backslash); end >= 0 {
+		return end
+	}
+	return len(sql)
+}
+
+// quotedEnd 返回从 sql[i] 开始、以 sql[i] 作为引号的字符串或标识符的结束引号之后的位置，未闭合时返回 -1
+func quotedEnd(sql string, i int, backslash bool) int {
+	quote := sql[i]
+	for j := i + 1; j < len(sql); j++ {
+		switch sql[j] {
+		case '\\':
+			if backslash {
+				j++
+			}
+		case quote:
+			if j+1 < len(sql) && sql[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return -1
+}
+
+// skipLine 跳过从 sql[i] 开始的单行注释，返回换行符的位置
+func skipLine(sql string, i int) int {
+	if end := strings.IndexByte(sql[i:], '\n'); end >= 0 {
+		return i + end
+	}
+	return len(sql)
+}
+
+// isEscapeStringPrefix 判断 sql[i] 处的单引号前是否为 PostgreSQL 转义字符串前缀 E/e
+func isEscapeStringPrefix(sql string, i int) bool {
+	return i > 0 && (sql[i-1] == 'E' || sql[i-1] == 'e') && (i == 1 || !isIdentByte(sql[i-2]))
+}
+
+// skipDollarQuoted 跳过从 sql[i] 开始的 $tag$...$tag$ 美元符号引用，返回结束标记之后的位置
+// sql[i] 处不是合法的开始标记时返回 i+1；引用未闭合时返回 len(sql)
+func skipDollarQuoted(sql string, i int) int {
+	j := i + 1
+	for j < len(sql) && isIdentByte(sql[j]) {
+		if j == i+1 && isDigit(sql[j]) {
+			return i + 1 // 标记不能以数字开头
+		}
+		j++
+	}
+	if j >= len(sql) || sql[j] != '$' {
+		return i + 1
+	}
+	tag := sql[i : j+1]
+	end := strings.Index(sql[j+1:], tag)
+	if end < 0 {
+		return len(sql)
+	}
+	return j + 1 + end + len(tag)
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isIdentByte 判断是否为 SQL 标识符中可出现的 ASCII 字符
+func isIdentByte(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || isDigit(c) || c == '_'
+}
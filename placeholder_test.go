@@ -0,0 +1,766 @@
+package sqlhelper
+
+import (
+	"database/sql"
+	"errors"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpandPg(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		vars    []interface{}
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "正常查询",
+			sql:  "SELECT * FROM users WHERE id = $1 AND name = $2",
+			vars: []interface{}{123, "john"},
+			want: "SELECT * FROM users WHERE id = 123 AND name = 'john'",
+		},
+		{
+			name: "乱序占位符",
+			sql:  "SELECT * FROM users WHERE name = $2 AND id = $1",
+			vars: []interface{}{123, "john"},
+			want: "SELECT * FROM users WHERE name = 'john' AND id = 123",
+		},
+		{
+			name: "同一占位符出现多次",
+			sql:  "SELECT * FROM t WHERE a = $1 OR b = $1",
+			vars: []interface{}{7},
+			want: "SELECT * FROM t WHERE a = 7 OR b = 7",
+		},
+		{
+			name: "两位数占位符",
+			sql:  "SELECT $10, $1, $2, $3, $4, $5, $6, $7, $8, $9",
+			vars: []interface{}{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+			want: "SELECT 10, 1, 2, 3, 4, 5, 6, 7, 8, 9",
+		},
+		{
+			name: "引号中的美元符号保持不变",
+			sql:  "SELECT '$5.00' AS price, id FROM t WHERE id = $1",
+			vars: []interface{}{1},
+			want: "SELECT '$5.00' AS price, id FROM t WHERE id = 1",
+		},
+		{
+			name: "转义单引号中的美元符号",
+			sql:  "SELECT 'it''s $1' FROM t WHERE id = $1",
+			vars: []interface{}{1},
+			want: "SELECT 'it''s $1' FROM t WHERE id = 1",
+		},
+		{
+			name: "E字符串中的反斜杠转义",
+			sql:  `SELECT E'a\'$1' FROM t WHERE id = $1`,
+			vars: []interface{}{1},
+			want: `SELECT E'a\'$1' FROM t WHERE id = 1`,
+		},
+		{
+			name: "美元符号引用中的占位符保持不变",
+			sql:  "SELECT $fn$ SELECT $1 $fn$, $1",
+			vars: []interface{}{2},
+			want: "SELECT $fn$ SELECT $1 $fn$, 2",
+		},
+		{
+			name: "注释中的占位符保持不变",
+			sql:  "SELECT * FROM t -- $2\nWHERE id = $1 /* $3 */",
+			vars: []interface{}{1},
+			want: "SELECT * FROM t -- $2\nWHERE id = 1 /* $3 */",
+		},
+		{
+			name: "未跟数字的美元符号",
+			sql:  "SELECT price$ FROM t WHERE id = $1",
+			vars: []interface{}{1},
+			want: "SELECT price$ FROM t WHERE id = 1",
+		},
+		{
+			name: "参数会被清理",
+			sql:  "SELECT * FROM users WHERE name = $1",
+			vars: []interface{}{"'; DROP TABLE users;--"},
+			want: "SELECT * FROM users WHERE name = '''; drop_table users;__'",
+		},
+		{
+			name:    "占位符越界",
+			sql:     "SELECT * FROM users WHERE id = $2",
+			vars:    []interface{}{1, 2, 3},
+			wantErr: true,
+		},
+		{
+			name:    "占位符为$0",
+			sql:     "SELECT * FROM users WHERE id = $0",
+			vars:    []interface{}{1},
+			wantErr: true,
+		},
+		{
+			name:    "参数未被引用",
+			sql:     "SELECT * FROM users WHERE id = $1",
+			vars:    []interface{}{1, "extra"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandPg(tt.sql, tt.vars)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ExpandPg(%q, %v) error = %v, wantErr %v", tt.sql, tt.vars, err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ExpandPg(%q, %v) = %q, want %q", tt.sql, tt.vars, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandNamed(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		args    map[string]interface{}
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "正常查询",
+			sql:  "SELECT * FROM users WHERE id = :id AND name = :name",
+			args: map[string]interface{}{"id": 123, "name": "john"},
+			want: "SELECT * FROM users WHERE id = 123 AND name = 'john'",
+		},
+		{
+			name: "同一参数出现多次",
+			sql:  "SELECT * FROM t WHERE a = :v OR b = :v",
+			args: map[string]interface{}{"v": 7},
+			want: "SELECT * FROM t WHERE a = 7 OR b = 7",
+		},
+		{
+			name: "引号中的冒号保持不变",
+			sql:  "SELECT * FROM t WHERE at = '12:30:00' AND id = :id",
+			args: map[string]interface{}{"id": 1},
+			want: "SELECT * FROM t WHERE at = '12:30:00' AND id = 1",
+		},
+		{
+			name: "类型转换不是占位符",
+			sql:  "SELECT :v::text",
+			args: map[string]interface{}{"v": "a"},
+			want: "SELECT 'a'::text",
+		},
+		{
+			name: "数字开头不是占位符",
+			sql:  "SELECT 1 FROM t WHERE id = :id_2 AND x = 12:30",
+			args: map[string]interface{}{"id_2": 2},
+			want: "SELECT 1 FROM t WHERE id = 2 AND x = 12:30",
+		},
+		{
+			name: "参数会被清理",
+			sql:  "SELECT * FROM users WHERE name = :name",
+			args: map[string]interface{}{"name": "'; DROP TABLE users;--"},
+			want: "SELECT * FROM users WHERE name = '''; drop_table users;__'",
+		},
+		{
+			name: "多余参数不报错",
+			sql:  "SELECT * FROM users WHERE id = :id",
+			args: map[string]interface{}{"id": 1, "extra": 2},
+			want: "SELECT * FROM users WHERE id = 1",
+		},
+		{
+			name:    "缺少参数",
+			sql:     "SELECT * FROM users WHERE id = :id AND name = :name",
+			args:    map[string]interface{}{"id": 1},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandNamed(tt.sql, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ExpandNamed(%q, %v) error = %v, wantErr %v", tt.sql, tt.args, err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ExpandNamed(%q, %v) = %q, want %q", tt.sql, tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandNamedSQLServer(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		args    map[string]interface{}
+		want    string
+		wantErr bool
+	}{
+		{"@占位符", "SELECT * FROM users WHERE id = @id AND name = @name",
+			map[string]interface{}{"id": 1, "name": "O'Brien"}, "SELECT * FROM users WHERE id = 1 AND name = 'O''Brien'", false},
+		{"引号中的@保持不变", "SELECT * FROM users WHERE email = 'a@b.com' AND id = @id",
+			map[string]interface{}{"id": 2}, "SELECT * FROM users WHERE email = 'a@b.com' AND id = 2", false},
+		{"系统变量不是占位符", "SELECT @@ROWCOUNT, @v", map[string]interface{}{"v": 4}, "SELECT @@ROWCOUNT, 4", false},
+		{"冒号不是占位符", "SELECT :id, @id", map[string]interface{}{"id": 5}, "SELECT :id, 5", false},
+		{"缺少参数", "SELECT * FROM users WHERE id = @id", map[string]interface{}{}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandNamed(tt.sql, tt.args, WithDialect(DialectSQLServer))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ExpandNamed(%q, %v) error = %v, wantErr %v", tt.sql, tt.args, err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ExpandNamed(%q, %v) = %q, want %q", tt.sql, tt.args, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ExpandNamed("SELECT @id", map[string]interface{}{}, WithDialect(DialectSQLServer)); err == nil || !strings.Contains(err.Error(), "@id") {
+		t.Errorf("缺少参数的错误应包含 @id，实际为 %v", err)
+	}
+}
+
+func TestExpandNamedArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		args     []interface{}
+		expected string
+	}{
+		{"冒号命名", "SELECT * FROM t WHERE id = :id", []interface{}{sql.Named("id", 42)}, "SELECT * FROM t WHERE id = 42"},
+		{"@命名", "SELECT * FROM t WHERE id = @id", []interface{}{sql.Named("id", 42)}, "SELECT * FROM t WHERE id = 42"},
+		{"混合位置和命名参数", "SELECT * FROM t WHERE a = ? AND id = :id AND b = ? OR id = @id",
+			[]interface{}{"x", sql.Named("id", 7), 2}, "SELECT * FROM t WHERE a = 'x' AND id = 7 AND b = 2 OR id = 7"},
+		{"命名参数被清理", "SELECT * FROM users WHERE name = :name",
+			[]interface{}{sql.Named("name", "'; DROP TABLE users; --")}, "SELECT * FROM users WHERE name = '''; drop_table users; __'"},
+		{"字符串和注释中不替换", "SELECT ':id', '@id', '?' -- :id ? @id\nFROM t WHERE id = :id",
+			[]interface{}{sql.Named("id", 1)}, "SELECT ':id', '@id', '?' -- :id ? @id\nFROM t WHERE id = 1"},
+		{"类型转换和系统变量", "SELECT :v::text, @@session.time_zone", []interface{}{sql.Named("v", "a")}, "SELECT 'a'::text, @@session.time_zone"},
+		{"未引用的命名参数", "SELECT ?", []interface{}{1, sql.Named("unused", 2)}, "SELECT 1"},
+		{"nil值", "SELECT :v", []interface{}{sql.Named("v", nil)}, "SELECT NULL"},
+		{"MySQL用户变量", "SET @x = 1; SELECT ?, @x", []interface{}{5}, "SET @x = 1; SELECT 5, @x"},
+		{"用户变量与@命名参数并存", "SELECT @x, @id", []interface{}{sql.Named("id", 3)}, "SELECT @x, 3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ExpandNamedArgs(tt.sql, tt.args...)
+			if err != nil {
+				t.Fatalf("ExpandNamedArgs() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("ExpandNamedArgs() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+
+	errorCases := []struct {
+		name string
+		sql  string
+		args []interface{}
+		want string
+	}{
+		{"缺少命名参数", "SELECT :id, :name", []interface{}{sql.Named("id", 1)}, ":name"},
+		{"名字为空", "SELECT 1", []interface{}{sql.Named("", 1)}, "名字不能为空"},
+		{"名字重复", "SELECT :a", []interface{}{sql.Named("a", 1), sql.Named("a", 2)}, "重复"},
+	}
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ExpandNamedArgs(tt.sql, tt.args...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ExpandNamedArgs() error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+
+	// 位置参数个数不符时返回 *ExpandError
+	var expandErr *ExpandError
+	if _, err := ExpandNamedArgs("SELECT ?, ?, :id", 1, sql.Named("id", 2)); !errors.As(err, &expandErr) || expandErr.Offset != 10 {
+		t.Errorf("ExpandNamedArgs() error = %v, want *ExpandError at 10", err)
+	}
+	if _, err := ExpandNamedArgs("SELECT :id", 1, sql.Named("id", 2)); !errors.As(err, &expandErr) || expandErr.Offset != -1 {
+		t.Errorf("ExpandNamedArgs() error = %v, want *ExpandError for extra args", err)
+	}
+}
+
+func TestUnusedNamedArgs(t *testing.T) {
+	sql := "SELECT * FROM t WHERE id = :id AND at > '10:00'"
+	args := map[string]interface{}{"id": 1, "b": 2, "a": 3}
+	got := UnusedNamedArgs(sql, args)
+	want := []string{"a", "b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UnusedNamedArgs(%q) = %v, want %v", sql, got, want)
+	}
+
+	// 与 ExpandNamed 相同的选项：SQL Server 风格的 @name
+	sql = "SELECT @a, @b, @@ROWCOUNT FROM t WHERE note = '@c'"
+	args = map[string]interface{}{"a": 1, "b": 2, "c": 3}
+	if got := UnusedNamedArgs(sql, args, WithDialect(DialectSQLServer)); !reflect.DeepEqual(got, []string{"c"}) {
+		t.Errorf("UnusedNamedArgs(%q, SQLServer) = %v, want [c]", sql, got)
+	}
+	if got := UnusedNamedArgs(sql, args); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("UnusedNamedArgs(%q) = %v, want [a b c]", sql, got)
+	}
+}
+
+func TestExpandSkipsQuotedAndComments(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		vars    []interface{}
+		dialect Dialect
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "单引号中的问号",
+			sql:  "SELECT '?' AS q, id FROM t WHERE id = ?",
+			vars: []interface{}{1},
+			want: "SELECT '?' AS q, id FROM t WHERE id = 1",
+		},
+		{
+			name: "双写单引号转义",
+			sql:  "SELECT 'it''s ?' FROM t WHERE id = ?",
+			vars: []interface{}{1},
+			want: "SELECT 'it''s ?' FROM t WHERE id = 1",
+		},
+		{
+			name: "MySQL反斜杠转义",
+			sql:  `SELECT 'it\'s ?' FROM t WHERE id = ?`,
+			vars: []interface{}{1},
+			want: `SELECT 'it\'s ?' FROM t WHERE id = 1`,
+		},
+		{
+			name: "双引号中的问号",
+			sql:  `SELECT "what?" FROM t WHERE id = ?`,
+			vars: []interface{}{1},
+			want: `SELECT "what?" FROM t WHERE id = 1`,
+		},
+		{
+			name: "反引号标识符中的问号",
+			sql:  "SELECT `col?` FROM t WHERE id = ?",
+			vars: []interface{}{1},
+			want: "SELECT `col?` FROM t WHERE id = 1",
+		},
+		{
+			name: "单行注释中的问号",
+			sql:  "SELECT * FROM t -- why?\nWHERE id = ?",
+			vars: []interface{}{1},
+			want: "SELECT * FROM t -- why?\nWHERE id = 1",
+		},
+		{
+			name: "井号注释中的问号",
+			sql:  "SELECT * FROM t # why?\nWHERE id = ?",
+			vars: []interface{}{1},
+			want: "SELECT * FROM t # why?\nWHERE id = 1",
+		},
+		{
+			name: "块注释中的问号",
+			sql:  "SELECT /* a? b? */ * FROM t WHERE id = ?",
+			vars: []interface{}{1},
+			want: "SELECT /* a? b? */ * FROM t WHERE id = 1",
+		},
+		{
+			name: "MySQL中不带空格的--不是注释",
+			sql:  "SELECT 1--? FROM t",
+			vars: []interface{}{1},
+			want: "SELECT 1--1 FROM t",
+		},
+		{
+			name:    "ANSI方言中反斜杠不是转义符",
+			sql:     `SELECT 'C:\' FROM t WHERE id = ?`,
+			vars:    []interface{}{1},
+			dialect: DialectANSI,
+			want:    `SELECT 'C:\' FROM t WHERE id = 1`,
+		},
+		{
+			name:    "MySQL无反斜杠转义模式中反斜杠不是转义符",
+			sql:     `SELECT 'C:\' FROM t # ?` + "\nWHERE id = ?",
+			vars:    []interface{}{1},
+			dialect: DialectMySQLNoBackslashEscapes,
+			want:    `SELECT 'C:\' FROM t # ?` + "\nWHERE id = 1",
+		},
+		{
+			name:    "引号中的问号不计入占位符",
+			sql:     "SELECT '?' FROM t WHERE id = ?",
+			vars:    []interface{}{1, 2},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandFor(tt.sql, tt.vars, tt.dialect)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ExpandFor(%q, %v) error = %v, wantErr %v", tt.sql, tt.vars, err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ExpandFor(%q, %v) = %q, want %q", tt.sql, tt.vars, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandFullWidthQuestionMark(t *testing.T) {
+	tests := []struct {
+		name      string
+		sql       string
+		vars      []interface{}
+		want      string
+		wantCount int
+		wantErr   bool
+	}{
+		{
+			name:      "全角问号不是占位符",
+			sql:       "SELECT * FROM t WHERE note = '为什么？' AND id = ?",
+			vars:      []interface{}{1},
+			want:      "SELECT * FROM t WHERE note = '为什么？' AND id = 1",
+			wantCount: 1,
+		},
+		{
+			name:      "引号外的全角问号原样保留",
+			sql:       "SELECT ？, ? FROM t",
+			vars:      []interface{}{1},
+			want:      "SELECT ？, 1 FROM t",
+			wantCount: 1,
+		},
+		{
+			name:    "全角问号不计入占位符个数",
+			sql:     "SELECT ？ FROM t WHERE id = ？",
+			vars:    []interface{}{1},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, n, err := ExpandWithCount(tt.sql, tt.vars)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExpandWithCount(%q, %v) error = %v, wantErr %v", tt.sql, tt.vars, err, tt.wantErr)
+			}
+			if got != tt.want || n != tt.wantCount {
+				t.Errorf("ExpandWithCount(%q, %v) = %q, %d, want %q, %d", tt.sql, tt.vars, got, n, tt.want, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestExpandEscapedQuestionMark(t *testing.T) {
+	tests := []struct {
+		name      string
+		sql       string
+		vars      []interface{}
+		want      string
+		wantCount int
+		wantErr   bool
+	}{
+		{
+			name:      "?? 输出字面量 ?",
+			sql:       "SELECT 'a' ?? x FROM t WHERE id = ?",
+			vars:      []interface{}{1},
+			want:      "SELECT 'a' ? x FROM t WHERE id = 1",
+			wantCount: 1,
+		},
+		{
+			name:      "??? 是字面量 ? 加一个占位符",
+			sql:       "SELECT ???, ?",
+			vars:      []interface{}{1, 2},
+			want:      "SELECT ?1, 2",
+			wantCount: 2,
+		},
+		{
+			name:      "引号和注释中的 ?? 原样保留",
+			sql:       "SELECT '??', `c??` FROM t /* ?? */ WHERE id = ? -- ??",
+			vars:      []interface{}{1},
+			want:      "SELECT '??', `c??` FROM t /* ?? */ WHERE id = 1 -- ??",
+			wantCount: 1,
+		},
+		{
+			name:      "只有 ?? 时不需要参数",
+			sql:       "SELECT data ?? 'key' FROM t",
+			want:      "SELECT data ? 'key' FROM t",
+			wantCount: 0,
+		},
+		{
+			name:    "?? 不消耗参数",
+			sql:     "SELECT ?? FROM t WHERE id = ?",
+			vars:    []interface{}{1, 2},
+			wantErr: true,
+		},
+		{
+			name:    "?? 不算占位符",
+			sql:     "SELECT ?? FROM t WHERE id = ??",
+			vars:    []interface{}{1},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, n, err := ExpandWithCount(tt.sql, tt.vars)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExpandWithCount(%q, %v) error = %v, wantErr %v", tt.sql, tt.vars, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				var expandErr *ExpandError
+				if !errors.As(err, &expandErr) || expandErr.Placeholders != CountPlaceholders(tt.sql) {
+					t.Errorf("ExpandWithCount(%q) error = %v, want *ExpandError with %d placeholders", tt.sql, err, CountPlaceholders(tt.sql))
+				}
+				return
+			}
+			if got != tt.want || n != tt.wantCount {
+				t.Errorf("ExpandWithCount(%q, %v) = %q, %d, want %q, %d", tt.sql, tt.vars, got, n, tt.want, tt.wantCount)
+			}
+			// 编译后的模板、ExpandMany 和 ExpandNamedArgs 的结果一致
+			q, err := Compile(tt.sql)
+			if err != nil {
+				t.Fatalf("Compile(%q) error = %v", tt.sql, err)
+			}
+			if got, err := q.Expand(tt.vars); err != nil || got != tt.want {
+				t.Errorf("CompiledQuery.Expand() = %q, %v, want %q", got, err, tt.want)
+			}
+			if got, err := ExpandMany(tt.sql, [][]interface{}{tt.vars}); err != nil || got[0] != tt.want {
+				t.Errorf("ExpandMany() = %q, %v, want %q", got, err, tt.want)
+			}
+			if got, err := ExpandNamedArgs(tt.sql, tt.vars...); err != nil || got != tt.want {
+				t.Errorf("ExpandNamedArgs() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountPlaceholdersIgnoresFullWidth(t *testing.T) {
+	sql := "SELECT ？ FROM t WHERE a = ？ AND b = ?"
+	if n := countPlaceholders(sql, DialectMySQL); n != 1 {
+		t.Errorf("countPlaceholders(%q) = %d, want 1", sql, n)
+	}
+}
+
+func TestCountPlaceholders(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want int
+	}{
+		{"SELECT 1", 0},
+		{"SELECT * FROM t WHERE a = ? AND b = ?", 2},
+		{"SELECT '?' AS q, `c?`, \"d?\" FROM t WHERE id = ?", 1},
+		{"SELECT * FROM t -- ?\nWHERE id = ? # ?\n/* ? */", 1},
+		{`SELECT 'it\'s ?' FROM t WHERE id = ?`, 1},
+		{"SELECT 1--? FROM t", 1},
+		{"SELECT ？ FROM t", 0},
+		{"SELECT ?? FROM t WHERE id = ???", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			got := CountPlaceholders(tt.sql)
+			if got != tt.want {
+				t.Errorf("CountPlaceholders(%q) = %d, want %d", tt.sql, got, tt.want)
+			}
+			// 与 Expand 实际替换的个数一致
+			vars := make([]interface{}, got)
+			for i := range vars {
+				vars[i] = i
+			}
+			if _, n, err := ExpandWithCount(tt.sql, vars); err != nil || n != got {
+				t.Errorf("ExpandWithCount(%q) = %d, %v, want %d", tt.sql, n, err, got)
+			}
+		})
+	}
+}
+
+func TestExpandPrepared(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		args     map[string]interface{}
+		wantSQL  string
+		wantArgs []interface{}
+		wantErr  bool
+	}{
+		{
+			name:     "按出现顺序展开",
+			sql:      "SELECT * FROM t WHERE name = :name AND id = :id",
+			args:     map[string]interface{}{"id": 1, "name": "'; DROP TABLE t;--"},
+			wantSQL:  "SELECT * FROM t WHERE name = ? AND id = ?",
+			wantArgs: []interface{}{"'; DROP TABLE t;--", 1},
+		},
+		{
+			name:     "同一参数出现多次",
+			sql:      "SELECT * FROM t WHERE a = :v OR b = :v",
+			args:     map[string]interface{}{"v": 7},
+			wantSQL:  "SELECT * FROM t WHERE a = ? OR b = ?",
+			wantArgs: []interface{}{7, 7},
+		},
+		{
+			name:     "切片展开为IN子句",
+			sql:      "SELECT * FROM t WHERE id IN (:ids) AND s = :s",
+			args:     map[string]interface{}{"ids": []int{1, 2, 3}, "s": "x"},
+			wantSQL:  "SELECT * FROM t WHERE id IN (?, ?, ?) AND s = ?",
+			wantArgs: []interface{}{1, 2, 3, "x"},
+		},
+		{
+			name:    "空切片渲染为NULL",
+			sql:     "SELECT * FROM t WHERE id IN (:ids)",
+			args:    map[string]interface{}{"ids": []string{}},
+			wantSQL: "SELECT * FROM t WHERE id IN (NULL)",
+		},
+		{
+			name:     "字节切片作为单个参数",
+			sql:      "INSERT INTO t (b) VALUES (:b)",
+			args:     map[string]interface{}{"b": []byte("ab")},
+			wantSQL:  "INSERT INTO t (b) VALUES (?)",
+			wantArgs: []interface{}{[]byte("ab")},
+		},
+		{
+			name:     "引号、注释和类型转换不受影响",
+			sql:      "SELECT ':x', :v::text -- :y",
+			args:     map[string]interface{}{"v": 1},
+			wantSQL:  "SELECT ':x', ?::text -- :y",
+			wantArgs: []interface{}{1},
+		},
+		{
+			name:     "引号和注释中的 ? 不受影响",
+			sql:      "SELECT '?', '??' /* ? */ FROM t WHERE id = :id",
+			args:     map[string]interface{}{"id": 1},
+			wantSQL:  "SELECT '?', '??' /* ? */ FROM t WHERE id = ?",
+			wantArgs: []interface{}{1},
+		},
+		{
+			name:    "缺少参数",
+			sql:     "SELECT :a",
+			args:    map[string]interface{}{},
+			wantErr: true,
+		},
+		{
+			name:     "TypedList 展开并按类型清理",
+			sql:      "SELECT * FROM t WHERE id IN (:ids) AND s IN (:empty)",
+			args:     map[string]interface{}{"ids": TypedList{Type: ParamTypeID, Values: []string{"u-1", "u 2"}}, "empty": TypedList{Type: ParamTypeID}},
+			wantSQL:  "SELECT * FROM t WHERE id IN (?, ?) AND s IN (NULL)",
+			wantArgs: []interface{}{"u-1", "u_2"},
+		},
+		{
+			name: "本包类型转换为驱动值",
+			sql:  "INSERT INTO t VALUES (:hex, :bin, :num, :json, :null, :dur, :int, :float)",
+			args: map[string]interface{}{
+				"hex": HexBytes("ab"), "bin": BinaryBytes("cd"), "num": Numeric("12.50"), "json": JSONValue{"k": "v"},
+				"null": JSONValue(nil), "dur": 90 * time.Second, "int": big.NewInt(7), "float": big.NewFloat(0.5),
+			},
+			wantSQL:  "INSERT INTO t VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			wantArgs: []interface{}{[]byte("ab"), []byte("cd"), "12.50", `{"k":"v"}`, nil, int64(90), "7", "0.5"},
+		},
+		{
+			name:     "切片元素同样转换",
+			sql:      "SELECT * FROM t WHERE n IN (:nums)",
+			args:     map[string]interface{}{"nums": []Numeric{"1", "2.5"}},
+			wantSQL:  "SELECT * FROM t WHERE n IN (?, ?)",
+			wantArgs: []interface{}{"1", "2.5"},
+		},
+		{
+			name:    "不合法的 Numeric",
+			sql:     "SELECT :n",
+			args:    map[string]interface{}{"n": Numeric("1; DROP TABLE t")},
+			wantErr: true,
+		},
+		{
+			name:    "混用 ? 位置占位符",
+			sql:     "SELECT * FROM t WHERE a = ? AND id = :id",
+			args:    map[string]interface{}{"id": 1},
+			wantErr: true,
+		},
+		{
+			name:    "?? 转义同样不支持",
+			sql:     "SELECT data ?? 'k' FROM t WHERE id = :id",
+			args:    map[string]interface{}{"id": 1},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotSQL, gotArgs, err := ExpandPrepared(tt.sql, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExpandPrepared(%q) error = %v, wantErr %v", tt.sql, err, tt.wantErr)
+			}
+			if gotSQL != tt.wantSQL || !reflect.DeepEqual(gotArgs, tt.wantArgs) {
+				t.Errorf("ExpandPrepared(%q) = %q, %v, want %q, %v", tt.sql, gotSQL, gotArgs, tt.wantSQL, tt.wantArgs)
+			}
+			if err == nil && CountPlaceholders(gotSQL) != len(gotArgs) {
+				t.Errorf("ExpandPrepared(%q) placeholders = %d, args = %d", tt.sql, CountPlaceholders(gotSQL), len(gotArgs))
+			}
+		})
+	}
+}
+
+func TestVerifyBalancedQuotes(t *testing.T) {
+	tests := []struct {
+		name       string
+		sql        string
+		dialect    Dialect
+		wantOffset int // -1 表示引号平衡
+	}{
+		{"无引号", "SELECT 1", DialectMySQL, -1},
+		{"普通字符串", "SELECT 'a', \"b\", `c`", DialectMySQL, -1},
+		{"双写单引号", "SELECT 'it''s'", DialectMySQL, -1},
+		{"MySQL反斜杠转义", `SELECT 'it\'s'`, DialectMySQL, -1},
+		{"ANSI反斜杠不是转义符", `SELECT 'a\', 'b'`, DialectANSI, -1},
+		{"ANSI反斜杠转义导致未闭合", `SELECT 'it\'s'`, DialectANSI, 13},
+		{"PostgreSQL的E字符串", `SELECT E'it\'s'`, DialectPostgres, -1},
+		{"PostgreSQL美元符号引用", "SELECT $$it's$$", DialectPostgres, -1},
+		{"注释中的引号", "SELECT 1 -- it's\n, /* ' */ 2 # '", DialectMySQL, -1},
+		{"未闭合的字符串", "SELECT 'abc", DialectMySQL, 7},
+		{"多出的引号", "SELECT 'a'b'", DialectMySQL, 11},
+		{"未闭合的标识符", "SELECT `name FROM t", DialectMySQL, 7},
+		{"结尾的反斜杠", `SELECT 'abc\'`, DialectMySQL, 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyBalancedQuotesFor(tt.sql, tt.dialect)
+			if tt.wantOffset < 0 {
+				if err != nil {
+					t.Errorf("VerifyBalancedQuotesFor(%q) error = %v, want nil", tt.sql, err)
+				}
+				return
+			}
+			var quoteErr *UnbalancedQuoteError
+			if !errors.As(err, &quoteErr) {
+				t.Fatalf("VerifyBalancedQuotesFor(%q) error = %v, want *UnbalancedQuoteError", tt.sql, err)
+			}
+			if quoteErr.Offset != tt.wantOffset || quoteErr.Quote != tt.sql[tt.wantOffset] {
+				t.Errorf("VerifyBalancedQuotesFor(%q) error = %+v, want offset %d", tt.sql, quoteErr, tt.wantOffset)
+			}
+		})
+	}
+
+	// 展开后的 SQL 在对应方言下总是引号平衡
+	inputs := []interface{}{
+		"'; DROP TABLE users; --",
+		`\'; DROP TABLE users; --`,
+		`abc\`,
+		"It's \"quoted\" `text`",
+		"＇ＯＲ＇1＇＝＇1",
+		"\x00\n\r\x1a",
+		[]byte("a'b"),
+	}
+	for _, d := range []Dialect{DialectMySQL, DialectANSI, DialectOracle, DialectSQLServer, DialectPostgres, DialectMySQLNoBackslashEscapes, DialectSQLite} {
+		for _, input := range inputs {
+			sql, err := ExpandFor("SELECT * FROM t WHERE a = ? AND b = 'x'", []interface{}{input}, d)
+			if err != nil {
+				t.Fatalf("ExpandFor(%q, %v) error = %v", input, d, err)
+			}
+			if err := VerifyBalancedQuotesFor(sql, d); err != nil {
+				t.Errorf("VerifyBalancedQuotesFor(%q, %v) error = %v", sql, d, err)
+			}
+		}
+	}
+	if err := VerifyBalancedQuotes("SELECT 'a'"); err != nil {
+		t.Errorf("VerifyBalancedQuotes() error = %v, want nil", err)
+	}
+}
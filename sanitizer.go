@@ -0,0 +1,51 @@
+package sqlhelper
+
+// Sanitizer 把处理器、推断器和方言打包在一起的独立清理实例
+// 包级函数 Expand、Literal 等使用全局处理器和推断器，SetGlobalProcessor 会影响进程内所有调用方；
+// 需要不同清理规则的子系统或并行运行的测试可以各自持有一个 Sanitizer，互不影响
+// 零值可用，等同于包级函数：Processor、Inferrer 为 nil 时使用全局实例，方言为 DialectMySQL
+// 创建后可被多个 goroutine 并发使用，但不应再修改其字段
+type Sanitizer struct {
+	Processor *TypeAwareProcessor // 字符串清理使用的处理器，nil 使用全局处理器
+	Inferrer  *TypeInferrer       // 字符串类型推断使用的推断器，nil 使用全局推断器
+	Dialect   Dialect             // 字符串字面量的转义方言
+	BoolAsInt bool                // bool 渲染为 1/0 而不是 true/false，同 WithBoolAsInt
+}
+
+// defaultSanitizer 包级函数使用的默认实例，始终跟随全局处理器和推断器
+var defaultSanitizer = &Sanitizer{}
+
+// NewSanitizer 创建使用默认规则的独立实例，与全局处理器和推断器完全隔离
+func NewSanitizer(d Dialect) *Sanitizer {
+	return &Sanitizer{
+		Processor: NewTypeAwareProcessor(),
+		Inferrer:  &TypeInferrer{},
+		Dialect:   d,
+	}
+}
+
+// config 返回与该实例对应的渲染配置
+func (s *Sanitizer) config() literalConfig {
+	return literalConfig{dialect: s.Dialect, processor: s.Processor, inferrer: s.Inferrer, boolAsInt: s.BoolAsInt}
+}
+
+// Expand 与包级 Expand 相同，但使用该实例的处理器、推断器和方言；opts 中的设置优先于实例字段
+func (s *Sanitizer) Expand(sql string, vars []interface{}, opts ...ExpandOption) (string, error) {
+	c := s.config()
+	for _, opt := range opts {
+		opt(&c)
+	}
+	out, _, err := c.expand(sql, vars)
+	return out, err
+}
+
+// Literal 与包级 Literal 相同，但使用该实例的处理器、推断器和方言
+func (s *Sanitizer) Literal(v interface{}) (string, error) {
+	return s.config().literal(v)
+}
+
+// ProcessString 用该实例的处理器按 paramType 清理字符串，不加引号
+func (s *Sanitizer) ProcessString(value string, paramType ParamType) string {
+	processor, _ := s.config().pipeline()
+	return processor.ProcessString(value, paramType)
+}
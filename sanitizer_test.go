@@ -0,0 +1,79 @@
+package sqlhelper
+
+import (
+	"strings"
+	"testing"
+)
+
+// prefixValidator 在结果前加上固定前缀的测试验证器
+type prefixValidator struct {
+	paramType ParamType
+	prefix    string
+}
+
+func (v prefixValidator) GetType() ParamType           { return v.paramType }
+func (v prefixValidator) Validate(value string) string { return v.prefix + value }
+
+func TestSanitizerIsolation(t *testing.T) {
+	paramType, _ := InspectString("hello")
+
+	for _, prefix := range []string{"a_", "b_", "c_"} {
+		prefix := prefix
+		t.Run(prefix, func(t *testing.T) {
+			t.Parallel()
+			s := NewSanitizer(DialectMySQL)
+			s.Processor.RegisterValidator(prefixValidator{paramType: paramType, prefix: prefix})
+
+			got, err := s.Expand("SELECT ?", []interface{}{"hello"})
+			if err != nil {
+				t.Fatalf("Expand() error = %v", err)
+			}
+			if want := "SELECT '" + prefix + "hello'"; got != want {
+				t.Errorf("Expand() = %q, want %q", got, want)
+			}
+			if got := s.ProcessString("x", paramType); got != prefix+"x" {
+				t.Errorf("ProcessString() = %q, want %q", got, prefix+"x")
+			}
+		})
+	}
+
+	// 全局处理器不受影响
+	if got, _ := Literal("hello"); got != "'hello'" {
+		t.Errorf("Literal() = %q, want %q", got, "'hello'")
+	}
+}
+
+func TestSanitizer(t *testing.T) {
+	// 零值等同于包级函数
+	var zero Sanitizer
+	sql := "SELECT * FROM t WHERE a = ? AND b IN (?)"
+	vars := []interface{}{`C:\dir`, []string{"x", "it's"}}
+	got, err := zero.Expand(sql, vars)
+	if err != nil {
+		t.Fatalf("Expand() error = %v", err)
+	}
+	if want, _ := Expand(sql, vars); got != want {
+		t.Errorf("Sanitizer{}.Expand() = %q, Expand() = %q", got, want)
+	}
+
+	// 方言
+	s := NewSanitizer(DialectANSI)
+	if got, _ := s.Literal(`it's C:\dir`); got != `'it''s C:\dir'` {
+		t.Errorf("Literal() = %q, want ANSI quoting", got)
+	}
+	if _, err := s.Expand("SELECT ?", nil); err == nil {
+		t.Error("Expand() with missing argument error = nil")
+	}
+
+	// 独立实例不跟随全局处理器
+	defer SetGlobalProcessor(nil)
+	global := NewTypeAwareProcessor()
+	global.RegisterValidator(prefixValidator{paramType: ParamTypeGeneric, prefix: "g_"})
+	SetGlobalProcessor(global)
+	if got := s.ProcessString("v", ParamTypeGeneric); strings.HasPrefix(got, "g_") {
+		t.Errorf("ProcessString() = %q, want isolated from global processor", got)
+	}
+	if got := zero.ProcessString("v", ParamTypeGeneric); got != "g_v" {
+		t.Errorf("Sanitizer{}.ProcessString() = %q, want global processor", got)
+	}
+}
@@ -1,27 +1,74 @@
 package sqlhelper
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"golang.org/x/text/unicode/norm"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 // ParamType 参数类型枚举
+// 0 到 ParamTypeCustomBase-1 保留给内置类型；自定义类型应使用 NewParamType 分配，
+// 避免不同的库手工选取同一个值。任意 ParamType 都可以通过 RegisterValidator 注册验证器
 type ParamType int
 
 const (
-	ParamTypeGeneric     ParamType = iota // 通用类型，默认处理
-	ParamTypeID                           // ID类型：项目ID、用户ID等，严格验证
-	ParamTypeName                         // 名称类型：项目名称、用户名等，中等验证
-	ParamTypeDescription                  // 描述类型：详细描述、备注等，宽松验证
+	ParamTypeGeneric       ParamType = iota // 通用类型，默认处理
+	ParamTypeID                             // ID类型：项目ID、用户ID等，严格验证
+	ParamTypeName                           // 名称类型：项目名称、用户名等，中等验证
+	ParamTypeDescription                    // 描述类型：详细描述、备注等，宽松验证
+	ParamTypeJSON                           // JSON类型：JSON 列的值，合法时原样保留
+	ParamTypeEmail                          // 邮箱类型：规范化并校验邮箱格式
+	ParamTypePhone                          // 电话类型：去掉格式字符，规范化为 E.164 形式
+	ParamTypeURL                            // URL类型：只允许 http、https 协议，输出规范形式
+	ParamTypeNumericString                  // 数字字符串类型：账号等以字符串存储的数字，只保留数字和前导零
 )
 
+// ParamTypeCustomBase NewParamType 分配的第一个值，小于它的值保留给内置类型
+const ParamTypeCustomBase ParamType = 1000
+
+// customParamTypes 已分配的自定义类型个数
+var customParamTypes atomic.Int64
+
+// NewParamType 分配一个未被使用的自定义参数类型，可安全地并发调用
+// 返回值从 ParamTypeCustomBase 开始递增，不会与内置类型或其他调用返回的值重复
+func NewParamType() ParamType {
+	return ParamTypeCustomBase + ParamType(customParamTypes.Add(1)-1)
+}
+
+// 各验证器的默认长度限制，按字符（rune）计
+const (
+	DefaultIDMaxLength          = 100
+	DefaultNameMaxLength        = 500
+	DefaultDescriptionMaxLength = 10000
+	DefaultGenericMaxLength     = 2000
+	// DefaultNumericStringMaxLength 数字字符串的默认最多位数
+	DefaultNumericStringMaxLength = 64
+)
+
+// NoLengthLimit 表示不限制长度，可赋值给验证器的 MaxLength 字段
+const NoLengthLimit = -1
+
+// DropIllegalChars 赋值给 IDValidator.Replacement，表示直接删除非法字符而不是替换
+const DropIllegalChars rune = -1
+
 // ParamValidator 参数验证器接口
 type ParamValidator interface {
 	// Validate 验证并清理输入，返回清理后的安全字符串
@@ -30,403 +77,2298 @@ type ParamValidator interface {
 	GetType() ParamType
 }
 
+// LengthLimiter 支持调整长度限制的验证器，TypeAwareProcessor.SetMaxLength 依赖此接口
+type LengthLimiter interface {
+	// WithMaxLength 返回长度限制为 maxLen 的验证器副本，maxLen <= 0 表示不限制
+	WithMaxLength(maxLen int) ParamValidator
+}
+
+// resolveMaxLength 把构造参数转换为 MaxLength 字段值，maxLen <= 0 表示不限制
+func resolveMaxLength(maxLen int) int {
+	if maxLen <= 0 {
+		return NoLengthLimit
+	}
+	return maxLen
+}
+
+// limitLength 按字符数截断 s，不会截断多字节字符；maxLength 为 0 时使用默认值 def，为负数时不限制
+func limitLength(s string, maxLength, def int) string {
+	if maxLength == 0 {
+		maxLength = def
+	}
+	if maxLength < 0 || len(s) <= maxLength {
+		return s // 字节数不超过限制时字符数必然不超过
+	}
+	n := 0
+	for i := range s {
+		if n == maxLength {
+			return s[:i]
+		}
+		n++
+	}
+	return s
+}
+
+// strictLimit ValidateStrict 使用的长度限制：reject 为 true 且超出限制时返回 *LengthError，否则同 limitLength
+func strictLimit(s string, maxLength, def int, reject bool, paramType ParamType) (string, error) {
+	if maxLength == 0 {
+		maxLength = def
+	}
+	if reject && maxLength >= 0 && len(s) > maxLength {
+		if n := utf8.RuneCountInString(s); n > maxLength {
+			return "", &LengthError{Type: paramType, Length: n, MaxLength: maxLength}
+		}
+	}
+	return limitLength(s, maxLength, def), nil
+}
+
+// LengthError 开启 ErrorOnOverflow 时，严格模式下输入超出长度限制返回的错误
+type LengthError struct {
+	Type      ParamType // 参数类型
+	Length    int       // 规范化后输入的字符数
+	MaxLength int       // 允许的最大字符数
+}
+
+func (e *LengthError) Error() string {
+	return fmt.Sprintf("长度 %d 超过限制 %d，超出 %d 个字符", e.Length, e.MaxLength, e.Length-e.MaxLength)
+}
+
+// StrictValidator 支持严格模式的验证器，检测到危险输入时返回 error 而不是静默改写
+type StrictValidator interface {
+	ParamValidator
+	// ValidateStrict 验证输入，检测到危险模式时返回 *InjectionError
+	ValidateStrict(value string) (string, error)
+}
+
+// PatternLister 可以列出自身所检查危险模式的验证器，用于审计和生成合规文档
+type PatternLister interface {
+	// Patterns 按应用顺序返回危险模式规则的副本，修改返回值不影响验证器
+	Patterns() []PatternRule
+}
+
+// InjectionError 严格模式下检测到危险模式时返回的错误
+type InjectionError struct {
+	Type    ParamType // 参数类型
+	Pattern string    // 命中的危险模式
+	Offset  int       // 命中位置在规范化后字符串中的字节偏移
+}
+
+func (e *InjectionError) Error() string {
+	return fmt.Sprintf("检测到危险模式 %q，位置 %d", e.Pattern, e.Offset)
+}
+
+// PatternHit ProcessStringAnnotated 返回的一处危险模式命中记录
+type PatternHit struct {
+	Pattern   string // 命中的危险模式
+	Offset    int    // 命中位置在规范化后字符串中的字节偏移
+	Rewritten bool   // 返回的结果中该处是否已被改写
+}
+
+// ValidateInfo ValidateWithInfo 返回的改写情况，用于审计输入是否被修改
+// 只统计危险模式替换、非法字符替换和截断；NFKC 规范化、空白合并等改写不计入，
+// 需要判断输入是否有任何变化时比较结果与原值
+type ValidateInfo struct {
+	Truncated    bool // 是否因超出长度限制被截断
+	OriginalLen  int  // 原始输入的字符（rune）数
+	Replacements int  // 危险模式、非法字符和分号被替换的处数
+}
+
+// validateWithInfo 执行 tv 的验证并统计改写情况
+func validateWithInfo(tv tracedValidator, value string) (string, ValidateInfo) {
+	info := ValidateInfo{OriginalLen: utf8.RuneCountInString(value)}
+	result := tv.validateTraced(value, trace{info: &info})
+	return result, info
+}
+
+// Step ExplainString 返回的一个处理阶段
+type Step struct {
+	Stage  string // 阶段名称，取值见 Stage 开头的常量
+	Before string // 进入该阶段前的值
+	After  string // 该阶段处理后的值
+}
+
+// ExplainString 中出现的处理阶段，按验证器中的先后顺序排列
+const (
+	StageNormalize      = "normalize"       // Unicode NFKC 规范化
+	StageWhitespace     = "whitespace"      // 空白符统一与合并
+	StageStripHTML      = "strip-html"      // 去除 HTML（DescriptionValidator.StripHTML）
+	StageHomoglyph      = "homoglyph"       // 同形字符折叠（FoldHomoglyphs）
+	StageFilter         = "filter"          // ID 非法字符替换为下划线
+	StagePatternReplace = "pattern-replace" // 危险模式替换
+	StageStackedQueries = "stacked-queries" // 堆叠查询分号处理（GenericValidator.BlockStackedQueries）
+	StageKeepOriginal   = "keep-original"   // 未命中危险模式时保留原值（KeepOriginal）
+	StageTruncate       = "truncate"        // 长度限制
+	StageValidate       = "validate"        // 非内置验证器的整体处理
+)
+
 // IDValidator ID类型验证器，严格限制只允许字母数字短横线下划线
-type IDValidator struct{}
+type IDValidator struct {
+	// MaxLength 最大字符数，0 使用默认值 DefaultIDMaxLength，NoLengthLimit 表示不限制
+	MaxLength int
+	// ErrorOnOverflow 为 true 时 ValidateStrict 对超出长度限制的输入返回 *LengthError 而不是截断；Validate 仍然截断
+	ErrorOnOverflow bool
+	// Replacement 替换非法字符所用的字符，0 使用下划线，DropIllegalChars 表示直接删除
+	// （project@123 变为 project123）；必须是 ID 允许的字符，否则仍使用下划线
+	Replacement rune
+}
+
+// NewIDValidator 创建指定长度限制的ID验证器，maxLen <= 0 表示不限制
+func NewIDValidator(maxLen int) IDValidator {
+	return IDValidator{MaxLength: resolveMaxLength(maxLen)}
+}
+
+// WithMaxLength 实现 LengthLimiter
+func (v IDValidator) WithMaxLength(maxLen int) ParamValidator {
+	v.MaxLength = resolveMaxLength(maxLen)
+	return v
+}
 
 func (v IDValidator) GetType() ParamType {
 	return ParamTypeID
 }
 
+// Patterns ID 类型按字符白名单清理，不检查危险模式，总是返回 nil
+func (v IDValidator) Patterns() []PatternRule {
+	return nil
+}
+
 func (v IDValidator) Validate(value string) string {
+	return v.validateTraced(value, trace{})
+}
+
+// ValidateWithInfo 与 Validate 相同，同时返回是否截断、替换了多少处等改写情况
+func (v IDValidator) ValidateWithInfo(value string) (string, ValidateInfo) {
+	return validateWithInfo(v, value)
+}
+
+func (v IDValidator) validateTraced(value string, tr trace) string {
 	// 1. Unicode规范化，将全角字符转换为半角
-	normalized := norm.NFKC.String(value)
+	normalized := tr.step(StageNormalize, value, norm.NFKC.String(value))
 
 	// 2. 只保留安全字符：字母、数字、短横线、下划线
 	result := strings.Builder{}
-	for _, r := range normalized {
-		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') ||
-			(r >= '0' && r <= '9') || r == '-' || r == '_' {
+	replacement := v.replacement()
+	for i, r := range normalized {
+		if isIDRune(r) {
 			result.WriteRune(r)
 		} else {
-			// 非法字符替换为下划线
-			result.WriteRune('_')
+			// 非法字符替换为下划线或指定的字符
+			if replacement != DropIllegalChars {
+				result.WriteRune(replacement)
+			}
+			tr.count(1)
+			tr.hit(PatternHit{Pattern: string(r), Offset: i, Rewritten: true})
 		}
 	}
 
-	cleaned := result.String()
+	filtered := tr.step(StageFilter, normalized, result.String())
 
 	// 3. 长度限制，防止过长输入
-	if len(cleaned) > 100 {
-		cleaned = cleaned[:100]
+	return tr.limit(filtered, v.MaxLength, DefaultIDMaxLength)
+}
+
+// ValidateStrict 严格模式：出现非法字符时返回 *InjectionError，而不是替换为下划线
+func (v IDValidator) ValidateStrict(value string) (string, error) {
+	normalized := norm.NFKC.String(value)
+	for i, r := range normalized {
+		if !isIDRune(r) {
+			return "", &InjectionError{Type: ParamTypeID, Pattern: string(r), Offset: i}
+		}
+	}
+	return strictLimit(normalized, v.MaxLength, DefaultIDMaxLength, v.ErrorOnOverflow, ParamTypeID)
+}
+
+// replacement 返回替换非法字符所用的字符
+func (v IDValidator) replacement() rune {
+	if v.Replacement == DropIllegalChars || (v.Replacement != 0 && isIDRune(v.Replacement)) {
+		return v.Replacement
 	}
+	return '_'
+}
 
-	return cleaned
+// isIDRune 判断是否为ID允许的字符：字母、数字、短横线、下划线
+func isIDRune(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') ||
+		(r >= '0' && r <= '9') || r == '-' || r == '_'
 }
 
 // DescriptionValidator 描述类型验证器，支持富文本内容，宽松验证
-type DescriptionValidator struct{}
+type DescriptionValidator struct {
+	// MaxLength 最大字符数，0 使用默认值 DefaultDescriptionMaxLength，NoLengthLimit 表示不限制
+	MaxLength int
+	// ErrorOnOverflow 为 true 时 ValidateStrict 对超出长度限制的输入返回 *LengthError 而不是截断；Validate 仍然截断
+	ErrorOnOverflow bool
+	// StripHTML 为 true 时去掉 HTML 标签和注释并保留其中的文本，<script>、<style> 连同内容一起去掉，
+	// 用于降低内容在网页中展示时的存储型 XSS 风险
+	StripHTML bool
+	// FoldHomoglyphs 为 true 时在检测危险模式前把西里尔、希腊字母中的同形字符替换为 ASCII 字母，
+	// 识别 uniоn 这类混入同形字符的关键字；替换是有损的，结果中的这些字符也会被改写
+	FoldHomoglyphs bool
+	// KeepComments 为 true 时不改写 --、/*、*/、# 等注释符号，保留 "cost -- estimate"、"#新品 上市" 这类正文原样；
+	// 只需保留话题标签中的 # 时可以改用 DisablePatterns("#")
+	// Literal、Expand 总是把值渲染为带引号的字符串字面量，引号内的注释符号只是普通文本，不会被当作注释；
+	// 只有在把结果拼接到引号之外时才需要保持默认的改写
+	KeepComments bool
+	// KeepOriginal 为 true 时，规范化后未命中任何危险模式且原值未超出长度限制的输入原样返回，
+	// 不做 NFKC 规范化和换行统一；开启 StripHTML 时含有 HTML 的输入仍按原有流程处理
+	KeepOriginal bool
+
+	disabled string // DisablePatterns 禁用的危险模式，见 appendDisabled
+}
+
+// NewDescriptionValidator 创建指定长度限制的描述验证器，maxLen <= 0 表示不限制
+func NewDescriptionValidator(maxLen int) DescriptionValidator {
+	return DescriptionValidator{MaxLength: resolveMaxLength(maxLen)}
+}
+
+// WithMaxLength 实现 LengthLimiter
+func (v DescriptionValidator) WithMaxLength(maxLen int) ParamValidator {
+	v.MaxLength = resolveMaxLength(maxLen)
+	return v
+}
 
 func (v DescriptionValidator) GetType() ParamType {
 	return ParamTypeDescription
 }
 
-func (v DescriptionValidator) Validate(value string) string {
-	// 1. Unicode规范化，将全角字符转换为半角
-	normalized := norm.NFKC.String(value)
+// Patterns 返回当前选项下生效的危险模式规则的副本；StripHTML 等选项的处理不在其中
+func (v DescriptionValidator) Patterns() []PatternRule {
+	return append([]PatternRule(nil), v.patterns()...)
+}
 
-	// 2. 基本的空白符统一处理（保持格式，不合并多个空格）
-	normalized = strings.ReplaceAll(normalized, "\r\n", "\n")
-	normalized = strings.ReplaceAll(normalized, "\r", "\n")
-
-	// 3. 检测和替换危险SQL关键字模式（更少的限制，允许某些关键字在描述中存在）
-	dangerousPatterns := map[string]string{
-		// 只替换最危险的SQL注入模式
-		"'; drop table":     "'; drop_table",
-		"'; delete from":    "'; delete_from", 
-		"'; truncate table": "'; truncate_table",
-		"'; insert into":    "'; insert_into",
-		"; drop table":      "; drop_table",
-		"; delete from":     "; delete_from",
-		"; truncate table":  "; truncate_table",
-		"; insert into":     "; insert_into",
-		"union select":      "union_select",
-		"union all select":  "union_all_select",
-		"xp_cmdshell":       "xp_cmd_shell",
-		"sp_executesql":     "sp_execute_sql",
-		// SQL注释在描述中可能是合法的，但仍然过滤连续的注释符号
-		"--":                "_-",  // 单个减号替换为下划线减号
-		"/*":                "/_*", // 注释开始
-		"*/":                "*_/", // 注释结束
-	}
-
-	// 转为小写进行检测，但保持原始大小写进行替换
-	lower := strings.ToLower(normalized)
-	result := normalized
-
-	for pattern, replacement := range dangerousPatterns {
-		if strings.Contains(lower, pattern) {
-			result = replaceCaseInsensitive(result, pattern, replacement)
-			lower = strings.ToLower(result) // 更新小写版本用于下一次检查
+// DisablePatterns 返回禁用了指定危险模式的验证器副本，用于消除误报；模式按 Patterns 返回的 Pattern 字段指定，
+// 大小写不敏感，不在列表中的模式被忽略。禁用会降低防护能力，只应禁用确认不会被利用的模式
+func (v DescriptionValidator) DisablePatterns(patterns ...string) DescriptionValidator {
+	v.disabled = appendDisabled(v.disabled, patterns)
+	return v
+}
+
+// patterns 返回当前选项下生效的危险模式规则
+func (v DescriptionValidator) patterns() []PatternRule {
+	rules := descriptionPatterns
+	if v.KeepComments {
+		rules = descriptionTextPatterns
+	}
+	return enabledPatterns(rules, v.disabled)
+}
+
+// descriptionPatterns 描述类型的危险模式（更少的限制，允许某些关键字在描述中存在），按顺序应用
+var descriptionPatterns = []PatternRule{
+	// 只替换最危险的SQL注入模式，带引号的堆叠查询优先
+	{"'; drop table", "'; drop_table"},
+	{"'; delete from", "'; delete_from"},
+	{"'; truncate table", "'; truncate_table"},
+	{"'; insert into", "'; insert_into"},
+	{"; drop table", "; drop_table"},
+	{"; delete from", "; delete_from"},
+	{"; truncate table", "; truncate_table"},
+	{"; insert into", "; insert_into"},
+	{"union all select", "union_all_select"},
+	{"union select", "union_select"},
+	// SQL注释在描述中可能是合法的，但仍然过滤连续的注释符号
+	{"/*", "/_*"}, // 注释开始
+	{"*/", "*_/"}, // 注释结束
+	{"--", "_-"},  // 单个减号替换为下划线减号
+	{"#", "_#"},   // MySQL 单行注释；正文中的话题标签（#标签）也会被标记，需要保留时见 KeepComments、DisablePatterns
+	{"xp_cmdshell", "xp_cmd_shell"},
+	{"sp_executesql", "sp_execute_sql"},
+	// 时间盲注，pg_sleep( 须在 sleep( 之前
+	{"waitfor delay", "waitfor_delay"},
+	{"benchmark(", "benchmark_("},
+	{"pg_sleep(", "pg_sleep_("},
+	{"sleep(", "sleep_("},
+}
+
+// descriptionTextPatterns 去掉注释符号后的描述危险模式，用于 KeepComments
+var descriptionTextPatterns = withoutPatterns(descriptionPatterns, "/*", "*/", "--", "#")
+
+// disabledSep 分隔 disabled 中的各个模式，危险模式中不会出现 NUL
+const disabledSep = "\x00"
+
+// appendDisabled 把 patterns 转为小写后并入 disabled，返回排序去重后以 disabledSep 连接的字符串
+// 验证器以字符串保存禁用的模式以保持可比较（==），禁用同样模式的两个验证器相等
+func appendDisabled(disabled string, patterns []string) string {
+	var all []string
+	if disabled != "" {
+		all = strings.Split(disabled, disabledSep)
+	}
+	for _, p := range patterns {
+		all = append(all, strings.ToLower(p))
+	}
+	sort.Strings(all)
+	kept := all[:0]
+	for i, p := range all {
+		if p != "" && (i == 0 || p != all[i-1]) {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, disabledSep)
+}
+
+// enabledPatternsKey 过滤结果的缓存键：规则集（以首个元素的地址区分）和 appendDisabled 生成的禁用集合
+type enabledPatternsKey struct {
+	rules    *PatternRule
+	disabled string
+}
+
+// enabledPatternsCache 缓存 enabledPatterns 的结果。禁用集合由代码中的 DisablePatterns 调用决定，种类有限
+var enabledPatternsCache sync.Map // enabledPatternsKey -> []PatternRule
+
+// enabledPatterns 返回 rules 去掉 disabled 中的模式后的规则，结果按规则集和禁用集合缓存，
+// 使禁用了模式的验证器在 Validate 时不必每次重新过滤；rules 须为包级的规则表，返回值不可修改
+func enabledPatterns(rules []PatternRule, disabled string) []PatternRule {
+	if disabled == "" || len(rules) == 0 {
+		return rules
+	}
+	key := enabledPatternsKey{&rules[0], disabled}
+	if cached, ok := enabledPatternsCache.Load(key); ok {
+		return cached.([]PatternRule)
+	}
+	kept := withoutPatterns(rules, strings.Split(disabled, disabledSep)...)
+	enabledPatternsCache.Store(key, kept)
+	return kept
+}
+
+// withoutPatterns 返回 rules 中去掉指定危险模式后的新切片，不修改 rules
+func withoutPatterns(rules []PatternRule, patterns ...string) []PatternRule {
+	var kept []PatternRule
+	for _, rule := range rules {
+		drop := false
+		for _, p := range patterns {
+			if rule.Pattern == p {
+				drop = true
+				break
+			}
+		}
+		if !drop {
+			kept = append(kept, rule)
 		}
 	}
+	return kept
+}
+
+func (v DescriptionValidator) Validate(value string) string {
+	return v.validateTraced(value, trace{})
+}
+
+// ValidateWithInfo 与 Validate 相同，同时返回是否截断、替换了多少处等改写情况
+func (v DescriptionValidator) ValidateWithInfo(value string) (string, ValidateInfo) {
+	return validateWithInfo(v, value)
+}
+
+func (v DescriptionValidator) validateTraced(value string, tr trace) string {
+	patterns := v.patterns()
+	// 快速路径：干净的 ASCII 输入无需规范化和模式替换；ExplainString 需要完整记录各阶段，不走快速路径
+	if tr.steps == nil && withinLimit(len(value), v.MaxLength, DefaultDescriptionMaxLength) && isSafeASCII(value, patterns) {
+		return value
+	}
+
+	normalized := v.normalize(value, tr)
+	if v.keepOriginal(value, normalized, patterns) {
+		return tr.step(StageKeepOriginal, normalized, value)
+	}
+
+	// 3. 检测和替换危险SQL关键字模式
+	result := tr.step(StagePatternReplace, normalized, applyPatterns(normalized, patterns, tr))
 
 	// 4. 长度限制（描述可以更长）
-	if len(result) > 10000 {
-		result = result[:10000]
+	return tr.limit(result, v.MaxLength, DefaultDescriptionMaxLength)
+}
+
+// ValidateStrict 严格模式：检测到危险模式时返回 *InjectionError，而不是改写
+func (v DescriptionValidator) ValidateStrict(value string) (string, error) {
+	normalized := v.normalize(value, trace{})
+	patterns := v.patterns()
+	if err := checkPatterns(normalized, patterns, ParamTypeDescription); err != nil {
+		return "", err
+	}
+	if v.keepOriginal(value, normalized, patterns) {
+		return value, nil
+	}
+	return strictLimit(normalized, v.MaxLength, DefaultDescriptionMaxLength, v.ErrorOnOverflow, ParamTypeDescription)
+}
+
+// keepOriginal 判断开启 KeepOriginal 时能否直接返回原值 value，normalized 是 value 规范化后的结果
+func (v DescriptionValidator) keepOriginal(value, normalized string, patterns []PatternRule) bool {
+	if !v.KeepOriginal || (v.StripHTML && strings.Contains(norm.NFKC.String(value), "<")) {
+		return false
 	}
+	return withinLimit(utf8.RuneCountInString(value), v.MaxLength, DefaultDescriptionMaxLength) &&
+		checkPatterns(normalized, patterns, ParamTypeDescription) == nil
+}
 
-	return result
+func (v DescriptionValidator) normalize(value string, tr trace) string {
+	// 1. Unicode规范化，将全角字符转换为半角
+	normalized := tr.step(StageNormalize, value, norm.NFKC.String(value))
+
+	// 2. 基本的空白符统一处理（保持格式，不合并多个空格）
+	unified := strings.ReplaceAll(normalized, "\r\n", "\n")
+	unified = strings.ReplaceAll(unified, "\r", "\n")
+	normalized = tr.step(StageWhitespace, normalized, unified)
+
+	if v.StripHTML {
+		normalized = tr.step(StageStripHTML, normalized, stripHTML(normalized))
+	}
+	if v.FoldHomoglyphs {
+		normalized = tr.step(StageHomoglyph, normalized, foldHomoglyphs(normalized))
+	}
+	return normalized
+}
+
+// HTML 清理用到的正则，按 stripHTML 中的顺序应用
+var (
+	htmlScriptRe  = regexp.MustCompile(`(?is)<script\b.*?(?:</script\s*>|$)`)
+	htmlStyleRe   = regexp.MustCompile(`(?is)<style\b.*?(?:</style\s*>|$)`)
+	htmlCommentRe = regexp.MustCompile(`(?s)<!--.*?(?:-->|$)`)
+	htmlTagRe     = regexp.MustCompile(`</?[A-Za-z][^<>]*>`)
+	htmlOpenRe    = regexp.MustCompile(`<([A-Za-z/!?])`)
+)
+
+// stripHTML 去掉 HTML 标签，保留标签之间的文本；未闭合的标签开头转义为 &lt;，避免被浏览器解析
+func stripHTML(s string) string {
+	if !strings.Contains(s, "<") {
+		return s
+	}
+	s = htmlScriptRe.ReplaceAllString(s, "")
+	s = htmlStyleRe.ReplaceAllString(s, "")
+	s = htmlCommentRe.ReplaceAllString(s, "")
+	s = htmlTagRe.ReplaceAllString(s, "")
+	return htmlOpenRe.ReplaceAllString(s, "&lt;$1")
 }
 
 // GenericValidator 通用验证器，默认验证策略，平衡安全性和兼容性
-type GenericValidator struct{}
+type GenericValidator struct {
+	// MaxLength 最大字符数，0 使用默认值 DefaultGenericMaxLength，NoLengthLimit 表示不限制
+	MaxLength int
+	// ErrorOnOverflow 为 true 时 ValidateStrict 对超出长度限制的输入返回 *LengthError 而不是截断；Validate 仍然截断
+	ErrorOnOverflow bool
+	// PreserveWhitespace 为 true 时保留制表符、换行等原始空白，不合并连续空白、不去掉首尾空白，
+	// 适合代码片段、多行地址等值；危险模式的替换照常进行
+	PreserveWhitespace bool
+	// BlockStackedQueries 为 true 时把引号子串之外的所有分号替换为空格，阻止堆叠查询；
+	// 严格模式下返回 *InjectionError
+	BlockStackedQueries bool
+	// FoldHomoglyphs 为 true 时在检测危险模式前把西里尔、希腊字母中的同形字符替换为 ASCII 字母，
+	// 识别 uniоn 这类混入同形字符的关键字；替换是有损的，结果中的这些字符也会被改写
+	FoldHomoglyphs bool
+	// PreserveCase 为 true 时危险模式替换后的形式沿用原文中匹配部分的大小写，
+	// 如 UnIoN sElEcT 替换为 UnIoN_sElEcT 而不是 union_select，便于审计时对照原文
+	PreserveCase bool
+
+	disabled string // DisablePatterns 禁用的危险模式，见 appendDisabled
+}
+
+// NewGenericValidator 创建指定长度限制的通用验证器，maxLen <= 0 表示不限制
+func NewGenericValidator(maxLen int) GenericValidator {
+	return GenericValidator{MaxLength: resolveMaxLength(maxLen)}
+}
+
+// WithMaxLength 实现 LengthLimiter
+func (v GenericValidator) WithMaxLength(maxLen int) ParamValidator {
+	v.MaxLength = resolveMaxLength(maxLen)
+	return v
+}
 
 func (v GenericValidator) GetType() ParamType {
 	return ParamTypeGeneric
 }
 
+// Patterns 返回当前生效的危险模式规则的副本；BlockStackedQueries 对分号的处理不在其中
+func (v GenericValidator) Patterns() []PatternRule {
+	return append([]PatternRule(nil), v.patterns()...)
+}
+
+// DisablePatterns 返回禁用了指定危险模式的验证器副本，用于消除误报；模式按 Patterns 返回的 Pattern 字段指定，
+// 大小写不敏感，不在列表中的模式被忽略。禁用会降低防护能力，只应禁用确认不会被利用的模式
+func (v GenericValidator) DisablePatterns(patterns ...string) GenericValidator {
+	v.disabled = appendDisabled(v.disabled, patterns)
+	return v
+}
+
+// patterns 返回去掉禁用模式后生效的危险模式规则
+func (v GenericValidator) patterns() []PatternRule {
+	return enabledPatterns(genericPatterns, v.disabled)
+}
+
+// genericPatterns 通用类型的危险模式，覆盖常见SQL注入关键字，按顺序应用
+var genericPatterns = []PatternRule{
+	// 堆叠查询，带引号的形式优先
+	{"'; drop table", "'; drop_table"},
+	{"'; delete from", "'; delete_from"},
+	{"'; truncate table", "'; truncate_table"},
+	{"'; insert into", "'; insert_into"},
+	{"'; update ", "'; update_"},
+	{"; drop table", "; drop_table"},
+	{"; delete from", "; delete_from"},
+	{"; truncate table", "; truncate_table"},
+	{"; insert into", "; insert_into"},
+	{"; update ", "; update_"},
+	// 联合查询
+	{"union all select", "union_all_select"},
+	{"union select", "union_select"},
+	// 布尔注入
+	{" or '1'='1", "_or_'1'='1"},
+	{" or 1=1", "_or_1=1"},
+	{" and 1=1", "_and_1=1"},
+	// 注释
+	{"/*", "/_*"},
+	{"*/", "*_/"},
+	{"--", "__"},
+	{"#", "_#"}, // MySQL 单行注释
+	// 危险存储过程
+	{"xp_cmdshell", "xp_cmd_shell"},
+	{"sp_executesql", "sp_execute_sql"},
+	// 时间盲注，pg_sleep( 须在 sleep( 之前
+	{"waitfor delay", "waitfor_delay"},
+	{"benchmark(", "benchmark_("},
+	{"pg_sleep(", "pg_sleep_("},
+	{"sleep(", "sleep_("},
+}
+
 func (v GenericValidator) Validate(value string) string {
+	return v.validateTraced(value, trace{})
+}
+
+// ValidateWithInfo 与 Validate 相同，同时返回是否截断、替换了多少处等改写情况
+func (v GenericValidator) ValidateWithInfo(value string) (string, ValidateInfo) {
+	return validateWithInfo(v, value)
+}
+
+func (v GenericValidator) validateTraced(value string, tr trace) string {
+	patterns := v.patterns()
+	// 快速路径：干净的 ASCII 输入无需规范化和模式替换；ExplainString 需要完整记录各阶段，不走快速路径
+	if tr.steps == nil && withinLimit(len(value), v.MaxLength, DefaultGenericMaxLength) && isSafeASCII(value, patterns) {
+		return value
+	}
+
+	normalized := v.normalize(value, tr)
+
+	// 3. 检测和替换常见SQL注入关键字模式
+	result := tr.step(StagePatternReplace, normalized, replacePatterns(normalized, patterns, tr, v.PreserveCase))
+	if v.BlockStackedQueries {
+		result = tr.step(StageStackedQueries, result, neutralizeSemicolons(result, tr, !v.PreserveWhitespace))
+	}
+
+	// 4. 长度限制
+	return tr.limit(result, v.MaxLength, DefaultGenericMaxLength)
+}
+
+// ValidateStrict 严格模式：检测到危险模式时返回 *InjectionError，而不是改写
+func (v GenericValidator) ValidateStrict(value string) (string, error) {
+	normalized := v.normalize(value, trace{})
+	err := checkPatterns(normalized, v.patterns(), ParamTypeGeneric)
+	if v.BlockStackedQueries {
+		if offsets := unquotedSemicolons(normalized); len(offsets) > 0 {
+			if hit, ok := err.(*InjectionError); !ok || offsets[0] < hit.Offset {
+				err = &InjectionError{Type: ParamTypeGeneric, Pattern: ";", Offset: offsets[0]}
+			}
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	return strictLimit(normalized, v.MaxLength, DefaultGenericMaxLength, v.ErrorOnOverflow, ParamTypeGeneric)
+}
+
+func (v GenericValidator) normalize(value string, tr trace) string {
+	var normalized string
+	if v.PreserveWhitespace {
+		// 只做Unicode规范化，空白原样保留
+		normalized = tr.step(StageNormalize, value, norm.NFKC.String(value))
+	} else {
+		normalized = normalizeInline(value, tr)
+	}
+	if v.FoldHomoglyphs {
+		normalized = tr.step(StageHomoglyph, normalized, foldHomoglyphs(normalized))
+	}
+	return normalized
+}
+
+// neutralizeSemicolons 把引号子串之外的分号替换为空格，collapse 为 true 时再重新合并空白
+func neutralizeSemicolons(s string, tr trace, collapse bool) string {
+	offsets := unquotedSemicolons(s)
+	if len(offsets) == 0 {
+		return s
+	}
+	tr.match(";", len(offsets))
+	if tr.annotate != nil {
+		for _, off := range offsets {
+			tr.hit(PatternHit{Pattern: ";", Offset: off})
+		}
+		return s
+	}
+	b := []byte(s)
+	for _, off := range offsets {
+		b[off] = ' '
+	}
+	if !collapse {
+		return string(b)
+	}
+	return strings.TrimSpace(whitespaceRe.ReplaceAllString(string(b), " "))
+}
+
+// unquotedSemicolons 返回 s 中不在成对的单引号或双引号子串内的分号位置
+// 没有闭合的引号不算引号子串，避免 x'; drop ... 借未闭合的引号绕过
+func unquotedSemicolons(s string) []int {
+	var offsets []int
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\'', '"':
+			if end := closedQuoteEnd(s, i); end > 0 {
+				i = end - 1
+			}
+		case ';':
+			offsets = append(offsets, i)
+		}
+	}
+	return offsets
+}
+
+// closedQuoteEnd 返回从 s[i] 开始的引号子串结束引号之后的位置，引号未闭合时返回 -1
+// 两个连续的引号和反斜杠都视为转义
+func closedQuoteEnd(s string, i int) int {
+	quote := s[i]
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case quote:
+			if j+1 < len(s) && s[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return -1
+}
+
+// NameValidator 名称类型验证器，支持中文，检测SQL注入关键字
+type NameValidator struct {
+	// MaxLength 最大字符数，0 使用默认值 DefaultNameMaxLength，NoLengthLimit 表示不限制
+	MaxLength int
+	// ErrorOnOverflow 为 true 时 ValidateStrict 对超出长度限制的输入返回 *LengthError 而不是截断；Validate 仍然截断
+	ErrorOnOverflow bool
+	// PreserveWhitespace 为 true 时保留原始空白，不合并连续空白、不去掉首尾空白，
+	// 危险模式的替换照常进行
+	PreserveWhitespace bool
+	// FoldHomoglyphs 为 true 时在检测危险模式前把西里尔、希腊字母中的同形字符替换为 ASCII 字母，
+	// 识别 uniоn 这类混入同形字符的关键字；替换是有损的，结果中的这些字符也会被改写
+	FoldHomoglyphs bool
+	// KeepOriginal 为 true 时，规范化后未命中任何危险模式且原值未超出长度限制的输入原样返回，
+	// 不做 NFKC 规范化和空白合并，避免干净数据的空格等被意外修改
+	KeepOriginal bool
+	// PreserveCase 为 true 时危险模式替换后的形式沿用原文中匹配部分的大小写，
+	// 如 UnIoN sElEcT 替换为 UnIoN_sElEcT 而不是 union_select，便于审计时对照原文
+	PreserveCase bool
+
+	disabled string // DisablePatterns 禁用的危险模式，见 appendDisabled
+}
+
+// NewNameValidator 创建指定长度限制的名称验证器，maxLen <= 0 表示不限制
+func NewNameValidator(maxLen int) NameValidator {
+	return NameValidator{MaxLength: resolveMaxLength(maxLen)}
+}
+
+// WithMaxLength 实现 LengthLimiter
+func (v NameValidator) WithMaxLength(maxLen int) ParamValidator {
+	v.MaxLength = resolveMaxLength(maxLen)
+	return v
+}
+
+func (v NameValidator) GetType() ParamType {
+	return ParamTypeName
+}
+
+// Patterns 返回当前生效的危险模式规则的副本
+func (v NameValidator) Patterns() []PatternRule {
+	return append([]PatternRule(nil), v.patterns()...)
+}
+
+// DisablePatterns 返回禁用了指定危险模式的验证器副本，用于消除误报，如禁用 concat 以保留 "Concatenation Corp"；
+// 模式按 Patterns 返回的 Pattern 字段指定，大小写不敏感，不在列表中的模式被忽略。
+// 禁用会降低防护能力，只应禁用确认不会被利用的模式
+func (v NameValidator) DisablePatterns(patterns ...string) NameValidator {
+	v.disabled = appendDisabled(v.disabled, patterns)
+	return v
+}
+
+// patterns 返回去掉禁用模式后生效的危险模式规则
+func (v NameValidator) patterns() []PatternRule {
+	return enabledPatterns(namePatterns, v.disabled)
+}
+
+// namePatterns 名称类型的危险模式，除注入关键字外还包括布尔、函数和时间盲注特征，按顺序应用
+var namePatterns = []PatternRule{
+	// 堆叠查询
+	{"'; drop table", "'; drop_table"},
+	{"'; delete from", "'; delete_from"},
+	{"'; insert into", "'; insert_into"},
+	{"'; update set", "'; update_set"},
+	// 联合查询
+	{"union all select", "union_all_select"},
+	{"union select", "union_select"},
+	// 布尔注入，具体的形式优先于单独的 or / and
+	{"' or '", "'_or_'"},
+	{"\" or \"", "\"_or_\""},
+	{"' and '", "'_and_'"},
+	{"\" and \"", "\"_and_\""},
+	{" or '1'='1", "_or_'1'='1"},
+	{" or 1=1", "_or_1=1"},
+	{" or ", "_or_"},
+	{" and ", "_and_"},
+	// 注释
+	{"/*", "/_*"},
+	{"*/", "*_/"},
+	{"--", "__"},
+	{"#", "_#"},
+	// 危险存储过程
+	{"xp_cmdshell", "xp_cmd_shell"},
+	{"sp_executesql", "sp_execute_sql"},
+	// 函数和时间盲注
+	{"ascii", "_ascii_"},
+	{"substring", "_substring_"},
+	{"concat", "_concat_"},
+	{"extractvalue", "_extractvalue_"},
+	{"waitfor", "_waitfor_"},
+	{"delay", "_delay_"},
+	{"benchmark(", "benchmark_("},
+	{"pg_sleep(", "pg_sleep_("},
+	{"sleep(", "sleep_("},
+}
+
+func (v NameValidator) Validate(value string) string {
+	return v.validateTraced(value, trace{})
+}
+
+// ValidateWithInfo 与 Validate 相同，同时返回是否截断、替换了多少处等改写情况
+func (v NameValidator) ValidateWithInfo(value string) (string, ValidateInfo) {
+	return validateWithInfo(v, value)
+}
+
+func (v NameValidator) validateTraced(value string, tr trace) string {
+	patterns := v.patterns()
+	// 快速路径：干净的 ASCII 输入无需规范化和模式替换；ExplainString 需要完整记录各阶段，不走快速路径
+	if tr.steps == nil && withinLimit(len(value), v.MaxLength, DefaultNameMaxLength) && isSafeASCII(value, patterns) {
+		return value
+	}
+
+	normalized := v.normalize(value, tr)
+	if v.keepOriginal(value, normalized, patterns) {
+		return tr.step(StageKeepOriginal, normalized, value)
+	}
+
+	// 3. 检测和替换危险SQL关键字模式
+	result := tr.step(StagePatternReplace, normalized, replacePatterns(normalized, patterns, tr, v.PreserveCase))
+
+	// 4. 长度限制
+	return tr.limit(result, v.MaxLength, DefaultNameMaxLength)
+}
+
+// ValidateStrict 严格模式：检测到危险模式时返回 *InjectionError，而不是改写
+func (v NameValidator) ValidateStrict(value string) (string, error) {
+	normalized := v.normalize(value, trace{})
+	patterns := v.patterns()
+	if err := checkPatterns(normalized, patterns, ParamTypeName); err != nil {
+		return "", err
+	}
+	if v.keepOriginal(value, normalized, patterns) {
+		return value, nil
+	}
+	return strictLimit(normalized, v.MaxLength, DefaultNameMaxLength, v.ErrorOnOverflow, ParamTypeName)
+}
+
+// keepOriginal 判断开启 KeepOriginal 时能否直接返回原值 value，normalized 是 value 规范化后的结果
+func (v NameValidator) keepOriginal(value, normalized string, patterns []PatternRule) bool {
+	return v.KeepOriginal && withinLimit(utf8.RuneCountInString(value), v.MaxLength, DefaultNameMaxLength) &&
+		checkPatterns(normalized, patterns, ParamTypeName) == nil
+}
+
+func (v NameValidator) normalize(value string, tr trace) string {
+	var normalized string
+	if v.PreserveWhitespace {
+		// 只做Unicode规范化，空白原样保留
+		normalized = tr.step(StageNormalize, value, norm.NFKC.String(value))
+	} else {
+		normalized = normalizeInline(value, tr)
+	}
+	if v.FoldHomoglyphs {
+		normalized = tr.step(StageHomoglyph, normalized, foldHomoglyphs(normalized))
+	}
+	return normalized
+}
+
+// normalizeInline 单行文本的规范化：Unicode规范化并把所有空白合并为单个空格
+func normalizeInline(value string, tr trace) string {
 	// 1. Unicode规范化，将全角字符转换为半角
-	normalized := norm.NFKC.String(value)
+	normalized := tr.step(StageNormalize, value, norm.NFKC.String(value))
 
-	// 2. 基本空白符处理
-	normalized = strings.ReplaceAll(normalized, "\t", " ")
-	normalized = strings.ReplaceAll(normalized, "\n", " ")
-	normalized = strings.ReplaceAll(normalized, "\r", " ")
+	// 2. 统一空白符处理
+	collapsed := strings.ReplaceAll(normalized, "\t", " ")
+	collapsed = strings.ReplaceAll(collapsed, "\n", " ")
+	collapsed = strings.ReplaceAll(collapsed, "\r", " ")
 	// 合并多个连续空格为单个空格
-	normalized = regexp.MustCompile(`\s+`).ReplaceAllString(normalized, " ")
-	normalized = strings.TrimSpace(normalized)
+	collapsed = whitespaceRe.ReplaceAllString(collapsed, " ")
+	return tr.step(StageWhitespace, normalized, strings.TrimSpace(collapsed))
+}
+
+// isSafeASCII 快速判断 s 能否跳过规范化和模式替换：s 只含 ASCII 字母数字、单个空格和少量标点，
+// 首尾不是空格，且不包含 patterns 中的任一模式。满足时完整路径的结果与 s 逐字节相同
+func isSafeASCII(s string, patterns []PatternRule) bool {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case isIDRune(rune(c)), c == '.', c == ',', c == '@', c == ':', c == '/':
+		case c == ' ':
+			if i == 0 || i == len(s)-1 || s[i+1] == ' ' {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	for _, rule := range patterns {
+		if indexFoldASCII(s, rule.Pattern) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// whitespaceRe 匹配连续空白符，预编译避免每次验证重复解析
+var whitespaceRe = regexp.MustCompile(`\s+`)
+
+// PatternRule 危险模式及其替换形式
+// 规则按切片顺序依次应用，较长、较具体的模式排在与之重叠的较短模式之前，
+// 保证同一输入每次都得到相同的输出
+type PatternRule struct {
+	Pattern     string // 小写的危险模式
+	Replacement string // 替换后的安全形式
+}
+
+// applyPatterns 按顺序大小写不敏感地把 s 中出现的危险模式替换为对应的安全形式
+// 每替换一处都会通过 tr 上报
+func applyPatterns(s string, patterns []PatternRule, tr trace) string {
+	return replacePatterns(s, patterns, tr, false)
+}
+
+// replacePatterns 同 applyPatterns；keepCase 为 true 时替换后的形式沿用原文中匹配部分的大小写
+func replacePatterns(s string, patterns []PatternRule, tr trace, keepCase bool) string {
+	if tr.annotate != nil {
+		annotatePatterns(s, patterns, tr)
+		return s
+	}
+
+	// 在折叠形式上检测，但保持原始大小写进行替换；折叠只做一次，每次替换同步更新折叠形式，
+	// 命中多个模式时不再对整个结果重新折叠
+	f := newFoldedString(s)
+	for _, rule := range patterns {
+		if !strings.Contains(f.lower, rule.Pattern) {
+			continue
+		}
+		var n int
+		f, n = f.replace(rule.Pattern, rule.Replacement, keepCase)
+		tr.match(rule.Pattern, n)
+	}
+	return f.s
+}
+
+// annotatePatterns 只记录 s 中每一处危险模式的位置，不做替换
+func annotatePatterns(s string, patterns []PatternRule, tr trace) {
+	lower, offsets := foldWithOffsets(s)
+	for _, rule := range patterns {
+		for from := 0; ; {
+			i := strings.Index(lower[from:], rule.Pattern)
+			if i < 0 {
+				break
+			}
+			tr.match(rule.Pattern, 1)
+			tr.hit(PatternHit{Pattern: rule.Pattern, Offset: offsetIn(offsets, from+i)})
+			from += i + len(rule.Pattern)
+		}
+	}
+}
+
+// checkPatterns 查找 s 中最早出现的危险模式，找到时返回 *InjectionError
+func checkPatterns(s string, patterns []PatternRule, paramType ParamType) error {
+	lower, offsets := foldWithOffsets(s)
+	var hit *InjectionError
+	for _, rule := range patterns {
+		offset := strings.Index(lower, rule.Pattern)
+		if offset < 0 {
+			continue
+		}
+		// 同一位置命中多个模式时取较长者，保证结果确定
+		if hit == nil || offset < hit.Offset || (offset == hit.Offset && len(rule.Pattern) > len(hit.Pattern)) {
+			hit = &InjectionError{Type: paramType, Pattern: rule.Pattern, Offset: offset}
+		}
+	}
+	if hit == nil {
+		return nil
+	}
+	hit.Offset = offsetIn(offsets, hit.Offset)
+	return hit
+}
+
+// trace 在一次验证过程中上报清理事件，零值不做任何事
+// 按值传递，避免在未命中危险模式时产生堆分配
+type trace struct {
+	onMatch   func(paramType ParamType, pattern string, original string)
+	paramType ParamType
+	original  string
+	annotate  *[]PatternHit // 非 nil 时只标注危险模式而不替换，命中记录追加到这里
+	steps     *[]Step       // 非 nil 时记录每个处理阶段，供 ExplainString 使用
+	info      *ValidateInfo // 非 nil 时统计改写情况，供 ValidateWithInfo 使用
+}
+
+// step 记录一个处理阶段并返回 after，便于在赋值时串联
+func (t trace) step(stage string, before, after string) string {
+	if t.steps != nil {
+		*t.steps = append(*t.steps, Step{Stage: stage, Before: before, After: after})
+	}
+	return after
+}
+
+// hit 在标注模式下记录一次命中
+func (t trace) hit(h PatternHit) {
+	if t.annotate != nil {
+		*t.annotate = append(*t.annotate, h)
+	}
+}
+
+// count 统计 n 处改写
+func (t trace) count(n int) {
+	if t.info != nil {
+		t.info.Replacements += n
+	}
+}
+
+// limit 执行长度限制并记录截断阶段
+func (t trace) limit(s string, maxLength, def int) string {
+	result := limitLength(s, maxLength, def)
+	if t.info != nil && len(result) < len(s) {
+		t.info.Truncated = true
+	}
+	return t.step(StageTruncate, s, result)
+}
+
+// match 上报危险模式 pattern 被替换了 n 次
+func (t trace) match(pattern string, n int) {
+	t.count(n)
+	if t.onMatch == nil {
+		return
+	}
+	for i := 0; i < n; i++ {
+		t.onMatch(t.paramType, pattern, t.original)
+	}
+}
+
+// tracedValidator 内置验证器实现的内部接口，验证时通过 trace 上报清理事件
+type tracedValidator interface {
+	validateTraced(value string, tr trace) string
+}
+
+// TypeAwareProcessor 类型感知处理器管理器，可安全地被多个 goroutine 并发使用
+type TypeAwareProcessor struct {
+	mu         sync.RWMutex // 保护 validators
+	validators map[ParamType]ParamValidator
+
+	// OnPatternMatch 可选的回调，ProcessString 每替换一处危险模式调用一次，
+	// 参数为参数类型、命中的危险模式和原始输入；为 nil 时不回调
+	// 只对内置的通用、名称、描述验证器生效，需在并发使用处理器之前设置
+	OnPatternMatch func(paramType ParamType, pattern string, original string)
+
+	stats atomic.Pointer[statsCollector] // EnableStats 之后非 nil
+}
+
+// NewTypeAwareProcessor 创建类型感知处理器
+func NewTypeAwareProcessor() *TypeAwareProcessor {
+	processor := &TypeAwareProcessor{
+		validators: make(map[ParamType]ParamValidator),
+	}
+	
+	// 注册所有验证器
+	processor.RegisterValidator(IDValidator{})
+	processor.RegisterValidator(NameValidator{})
+	processor.RegisterValidator(DescriptionValidator{})
+	processor.RegisterValidator(GenericValidator{})
+	processor.RegisterValidator(JSONValidator{})
+	processor.RegisterValidator(EmailValidator{})
+	processor.RegisterValidator(PhoneValidator{})
+	processor.RegisterValidator(URLValidator{})
+	processor.RegisterValidator(NumericStringValidator{})
+	
+	return processor
+}
+
+// NewEmptyProcessor 创建没有注册任何验证器的处理器，用于从零开始组装规则
+// 未注册的类型与 NewTypeAwareProcessor 一样交给通用验证器处理；没有注册通用验证器时使用默认的 GenericValidator{}，
+// 因此空处理器仍会清理危险模式，而不是原样放行
+func NewEmptyProcessor() *TypeAwareProcessor {
+	return &TypeAwareProcessor{
+		validators: make(map[ParamType]ParamValidator),
+	}
+}
+
+// Clone 返回处理器的副本，之后对副本注册验证器、调整长度限制等不影响原处理器，反之亦然
+// 适合以全局处理器或默认处理器为基础，为不同租户定制规则。OnPatternMatch 一并复制；
+// 原处理器开启了统计时副本同样开启，但计数从零开始、互相独立。验证器本身按值复制，指针类型的自定义验证器仍然共享
+func (tap *TypeAwareProcessor) Clone() *TypeAwareProcessor {
+	tap.mu.RLock()
+	defer tap.mu.RUnlock()
+	clone := &TypeAwareProcessor{
+		validators:     make(map[ParamType]ParamValidator, len(tap.validators)),
+		OnPatternMatch: tap.OnPatternMatch,
+	}
+	for paramType, validator := range tap.validators {
+		clone.validators[paramType] = validator
+	}
+	if tap.stats.Load() != nil {
+		clone.EnableStats()
+	}
+	return clone
+}
+
+// RegisterValidator 注册验证器
+func (tap *TypeAwareProcessor) RegisterValidator(validator ParamValidator) {
+	tap.mu.Lock()
+	defer tap.mu.Unlock()
+	tap.validators[validator.GetType()] = validator
+}
+
+// GetValidator 获取指定类型的验证器
+func (tap *TypeAwareProcessor) GetValidator(paramType ParamType) ParamValidator {
+	tap.mu.RLock()
+	defer tap.mu.RUnlock()
+	if validator, exists := tap.validators[paramType]; exists {
+		return validator
+	}
+	// 默认返回通用验证器
+	if validator, exists := tap.validators[ParamTypeGeneric]; exists {
+		return validator
+	}
+	return GenericValidator{}
+}
+
+// ProcessString 处理字符串参数，使用指定类型的验证器
+func (tap *TypeAwareProcessor) ProcessString(value string, paramType ParamType) string {
+	result := tap.processString(value, paramType)
+	logSanitization(paramType, value, result)
+	return result
+}
+
+func (tap *TypeAwareProcessor) processString(value string, paramType ParamType) string {
+	validator := tap.GetValidator(paramType)
+	stats := tap.stats.Load()
+	if tap.OnPatternMatch == nil && stats == nil {
+		return validator.Validate(value)
+	}
+	traced, ok := validator.(tracedValidator)
+	if !ok {
+		stats.record(paramType, ValidateInfo{})
+		return validator.Validate(value)
+	}
+	tr := trace{onMatch: tap.OnPatternMatch, paramType: paramType, original: value}
+	if stats == nil {
+		return traced.validateTraced(value, tr)
+	}
+	var info ValidateInfo
+	tr.info = &info
+	result := traced.validateTraced(value, tr)
+	stats.record(paramType, info)
+	return result
+}
+
+// ProcessRunes 与 ProcessString 相同，但直接接收和返回 []rune，便于对接按 rune 切分输入的分词器
+// 结果与 ProcessString(string(value), paramType) 逐字符一致；value 中的非法码点按 string 转换的规则变为 U+FFFD
+func (tap *TypeAwareProcessor) ProcessRunes(value []rune, paramType ParamType) []rune {
+	return []rune(tap.ProcessString(string(value), paramType))
+}
+
+// ProcessStringAnnotated 检测危险模式但不改写，返回保留原文的结果和按位置排序的命中记录，由调用方决定如何处理
+// 结果只经过规范化和长度限制；ID验证器仍会把非法字符替换为下划线，对应记录的 Rewritten 为 true
+// 验证器不是内置验证器时退化为 ProcessString，不返回命中记录
+func (tap *TypeAwareProcessor) ProcessStringAnnotated(value string, paramType ParamType) (string, []PatternHit) {
+	validator := tap.GetValidator(paramType)
+	traced, ok := validator.(tracedValidator)
+	if !ok {
+		return validator.Validate(value), nil
+	}
+	var hits []PatternHit
+	result := traced.validateTraced(value, trace{onMatch: tap.OnPatternMatch, paramType: paramType, original: value, annotate: &hits})
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Offset < hits[j].Offset })
+	return result, hits
+}
+
+// ExplainString 诊断用：按顺序返回 value 经过 paramType 对应验证器时每个处理阶段前后的值，
+// 用于排查合法输入为何被改写。最后一步的 After 与 ProcessString 的结果相同，不会触发 OnPatternMatch
+// 验证器不是内置验证器时只返回一个 StageValidate 阶段
+func (tap *TypeAwareProcessor) ExplainString(value string, paramType ParamType) []Step {
+	validator := tap.GetValidator(paramType)
+	traced, ok := validator.(tracedValidator)
+	if !ok {
+		return []Step{{Stage: StageValidate, Before: value, After: validator.Validate(value)}}
+	}
+	var steps []Step
+	traced.validateTraced(value, trace{paramType: paramType, original: value, steps: &steps})
+	return steps
+}
+
+// ProcessStringStrict 以严格模式处理字符串参数，检测到危险模式时返回 error
+// 验证器未实现 StrictValidator 时退化为 ProcessString
+func (tap *TypeAwareProcessor) ProcessStringStrict(value string, paramType ParamType) (string, error) {
+	validator := tap.GetValidator(paramType)
+	var (
+		result string
+		err    error
+	)
+	if strict, ok := validator.(StrictValidator); ok {
+		result, err = strict.ValidateStrict(value)
+	} else {
+		result = validator.Validate(value)
+	}
+	tap.stats.Load().recordStrict(paramType, err)
+	if err == nil {
+		logSanitization(paramType, value, result)
+	}
+	return result, err
+}
+
+// SetMaxLength 覆盖指定类型验证器的长度限制，maxLen <= 0 表示不限制
+// 该类型未注册验证器，或验证器未实现 LengthLimiter 时返回 error
+func (tap *TypeAwareProcessor) SetMaxLength(paramType ParamType, maxLen int) error {
+	tap.mu.Lock()
+	defer tap.mu.Unlock()
+	validator, exists := tap.validators[paramType]
+	if !exists {
+		return fmt.Errorf("类型 %d 未注册验证器", paramType)
+	}
+	limiter, ok := validator.(LengthLimiter)
+	if !ok {
+		return fmt.Errorf("类型 %d 的验证器 %T 不支持设置长度限制", paramType, validator)
+	}
+	tap.validators[paramType] = limiter.WithMaxLength(maxLen)
+	return nil
+}
+
+// UnicodeRange 闭区间的 Unicode 码点范围
+type UnicodeRange struct {
+	Lo, Hi rune
+}
+
+// InferOptions 类型推断选项，决定哪些内容被识别为名称类型
+type InferOptions struct {
+	// NameRanges 包含这些范围内的字符即推断为名称类型
+	NameRanges []UnicodeRange
+	// NameKeywords 包含这些关键字即推断为名称类型
+	NameKeywords []string
+	// Rules 自定义推断规则，按顺序在内置规则之前检查，第一个匹配的规则决定类型
+	Rules []InferRule
+}
+
+// InferRule 自定义类型推断规则：Match 返回 true 时推断为 Type
+type InferRule struct {
+	Type  ParamType
+	Match func(value string) bool
+}
+
+// DefaultInferOptions 返回默认推断选项：中文字符和常见的项目、地址关键字
+func DefaultInferOptions() InferOptions {
+	return InferOptions{
+		NameRanges: []UnicodeRange{
+			{0x4e00, 0x9fff}, // 中文基本汉字
+			{0x3400, 0x4dbf}, // 中文扩展A
+			{0xf900, 0xfaff}, // 中文兼容汉字
+		},
+		NameKeywords: []string{"项目", "小区", "大厦", "广场", "中心", "花园", "公寓", "别墅", "期", "区", "号"},
+	}
+}
+
+// defaultInferOptions 零值 TypeInferrer 使用的推断选项
+var defaultInferOptions = DefaultInferOptions()
+
+// TypeInferrer 类型推断器，根据字符串内容推断参数类型
+// 零值使用 DefaultInferOptions，需要自定义名称识别规则时使用 NewTypeInferrer
+type TypeInferrer struct {
+	options *InferOptions
+}
+
+// NewTypeInferrer 创建使用自定义推断选项的类型推断器
+func NewTypeInferrer(opts InferOptions) *TypeInferrer {
+	return &TypeInferrer{options: &opts}
+}
+
+// InferType 推断参数类型
+func (ti *TypeInferrer) InferType(value string) ParamType {
+	// 长度检查优先级最高
+	if len(value) == 0 {
+		return ParamTypeGeneric
+	}
+
+	// 自定义规则优先于内置规则
+	for _, rule := range ti.opts().Rules {
+		if rule.Match(value) {
+			return rule.Type
+		}
+	}
+
+	// ID类型检测：纯字母数字组合，通常较短
+	if len(value) <= 100 {
+		isID := true
+		for _, r := range value {
+			if !isIDRune(r) {
+				isID = false
+				break
+			}
+		}
+		if isID {
+			return ParamTypeID
+		}
+	}
+
+	// 描述类型检测：长度超过500或包含换行符
+	if len(value) > 500 || strings.Contains(value, "\n") || strings.Contains(value, "\r") {
+		return ParamTypeDescription
+	}
+
+	// 名称类型检测：包含指定范围的字符（默认为中文）或常见名称模式
+	if ti.isName(value) {
+		return ParamTypeName
+	}
+
+	// 默认返回通用类型
+	return ParamTypeGeneric
+}
+
+// opts 返回生效的推断选项，零值 TypeInferrer 使用 defaultInferOptions
+func (ti *TypeInferrer) opts() *InferOptions {
+	if ti.options != nil {
+		return ti.options
+	}
+	return &defaultInferOptions
+}
+
+// withRule 返回在 ti 的选项末尾追加了规则的新推断器，不修改 ti
+func (ti *TypeInferrer) withRule(rule InferRule) *TypeInferrer {
+	opts := *ti.opts()
+	opts.Rules = append(opts.Rules[:len(opts.Rules):len(opts.Rules)], rule)
+	return NewTypeInferrer(opts)
+}
+
+func (ti *TypeInferrer) isName(value string) bool {
+	opts := ti.opts()
+
+	if len(opts.NameRanges) > 0 {
+		for _, r := range value {
+			for _, rg := range opts.NameRanges {
+				if r >= rg.Lo && r <= rg.Hi {
+					return true
+				}
+			}
+		}
+	}
+
+	// 检测常见名称模式
+	for _, keyword := range opts.NameKeywords {
+		if strings.Contains(value, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// 全局类型感知处理器和类型推断器实例，Expand、Literal 等包级函数使用它们清理字符串
+var (
+	globalMu        sync.RWMutex
+	globalProcessor = NewTypeAwareProcessor()
+	globalInferrer  = &TypeInferrer{}
+	enumMappers     []func(v interface{}) (string, bool)
+)
+
+// SetGlobalProcessor 替换包级函数使用的类型感知处理器，传入 nil 恢复默认处理器
+// 会影响进程内所有调用方，应在程序启动时、开始处理请求之前设置
+func SetGlobalProcessor(processor *TypeAwareProcessor) {
+	if processor == nil {
+		processor = NewTypeAwareProcessor()
+	}
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	globalProcessor = processor
+}
+
+// SetGlobalInferrer 替换包级函数使用的类型推断器，传入 nil 恢复默认推断器
+// 会影响进程内所有调用方，应在程序启动时、开始处理请求之前设置
+func SetGlobalInferrer(inferrer *TypeInferrer) {
+	if inferrer == nil {
+		inferrer = &TypeInferrer{}
+	}
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	globalInferrer = inferrer
+}
+
+// sanitizationLogger SetSanitizationLogger 设置的回调，未设置时为 nil
+var sanitizationLogger atomic.Pointer[func(t ParamType, before, after string)]
+
+// SetSanitizationLogger 设置全局的清理日志回调，任何处理器的 ProcessString、ProcessBytes、ProcessStringStrict
+// （以及经由它们的 Expand、Literal 等）在清理改变了字符串时调用一次，参数为参数类型、清理前和清理后的值，
+// 可用于记录审计日志；值未被改变时不回调。回调会被并发调用，应尽快返回
+// 传入 nil 取消回调，未设置时不产生额外开销
+func SetSanitizationLogger(logger func(t ParamType, before, after string)) {
+	if logger == nil {
+		sanitizationLogger.Store(nil)
+		return
+	}
+	sanitizationLogger.Store(&logger)
+}
+
+// logSanitization 在 before 与 after 不同且设置了清理日志回调时调用回调
+func logSanitization(paramType ParamType, before, after string) {
+	if logger := sanitizationLogger.Load(); logger != nil && before != after {
+		(*logger)(paramType, before, after)
+	}
+}
+
+// globalPipeline 返回当前的全局处理器和推断器
+func globalPipeline() (*TypeAwareProcessor, *TypeInferrer) {
+	globalMu.RLock()
+	defer globalMu.RUnlock()
+	return globalProcessor, globalInferrer
+}
+
+// RegisterType 把验证器 v 注册到全局处理器，并把推断规则 inferRule 追加到全局推断器，
+// 使 Expand、Literal 等包级函数把 inferRule 返回 true 的字符串交给 v 处理；inferRule 为 nil 时只注册验证器
+// 推断规则在内置规则之前、按注册顺序检查。v.GetType() 必须等于 t，否则 panic
+// 会影响进程内所有调用方，应在程序启动时、开始处理请求之前注册
+func RegisterType(t ParamType, v ParamValidator, inferRule func(value string) bool) {
+	if v.GetType() != t {
+		panic(fmt.Sprintf("sqlhelper: RegisterType 的类型 %d 与验证器的类型 %d 不一致", t, v.GetType()))
+	}
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	globalProcessor.RegisterValidator(v)
+	if inferRule != nil {
+		// 替换而不是修改推断器，正在使用旧推断器的调用不受影响
+		globalInferrer = globalInferrer.withRule(InferRule{Type: t, Match: inferRule})
+	}
+}
+
+// RegisterEnumMapper 注册一个枚举映射函数，Literal、Expand 等在按类型渲染值之前依次调用已注册的映射函数，
+// 第一个返回 true 的映射函数的结果作为字符串字面量渲染（如把 Status(2) 渲染为 'shipped'）
+// 映射函数应只认领自己负责的类型，对其他值返回 false；传入 nil 不做任何事
+// 会影响进程内所有调用方，应在程序启动时、开始处理请求之前注册
+func RegisterEnumMapper(mapper func(v interface{}) (string, bool)) {
+	if mapper == nil {
+		return
+	}
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	// 复制后追加，已被读取的切片不受影响
+	enumMappers = append(enumMappers[:len(enumMappers):len(enumMappers)], mapper)
+}
+
+// mapEnum 依次调用已注册的枚举映射函数，返回第一个认领 v 的结果
+func mapEnum(v interface{}) (string, bool) {
+	globalMu.RLock()
+	mappers := enumMappers
+	globalMu.RUnlock()
+	for _, mapper := range mappers {
+		if s, ok := mapper(v); ok {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// Expand 把带 ? 占位符的 SQL 展开成可直接执行的纯文本 SQL
+// 字符串、引号标识符和注释（--、/* */、MySQL 的 #）中的 ? 不是占位符
+// 只有 ASCII 的 ? 是占位符，SQL 不做 Unicode 规范化，全角问号 ？ 始终作为普通文本原样保留
+// 连续的 ?? 输出为一个字面量 ?，不消耗参数；字符串和注释中的 ?? 原样保留
+// 如果占位符数量与参数个数不符，或出现未知类型，返回 error
+// 可以通过 opts 按调用指定方言、严格模式等，见 ExpandOption；不传时使用默认行为
+func Expand(sql string, vars []interface{}, opts ...ExpandOption) (string, error) {
+	return defaultSanitizer.Expand(sql, vars, opts...)
+}
+
+// ExpandFor 与 Expand 相同，但按指定数据库方言转义字符串字面量
+func ExpandFor(sql string, vars []interface{}, d Dialect) (string, error) {
+	return (&Sanitizer{Dialect: d}).Expand(sql, vars)
+}
+
+// ExpandContext 与 Expand 相同，但在展开过程中定期检查 ctx，ctx 被取消或超时时提前返回 ctx.Err()
+func ExpandContext(ctx context.Context, sql string, vars []interface{}) (string, error) {
+	out, _, err := literalConfig{ctx: ctx}.expand(sql, vars)
+	return out, err
+}
+
+// ctxCheckInterval 每替换多少个占位符（或展开切片、TypedList 的多少个元素）检查一次 context
+const ctxCheckInterval = 64
+
+// checkContext n 为已处理的占位符或元素个数，每 ctxCheckInterval 个返回一次 ctx.Err()；没有 ctx 时返回 nil
+func (c literalConfig) checkContext(n int) error {
+	if c.ctx != nil && n%ctxCheckInterval == 0 {
+		return c.ctx.Err()
+	}
+	return nil
+}
+
+// ExpandWithCount 与 Expand 相同，同时返回实际替换的占位符个数
+// 出错时返回的个数为出错前已成功替换的占位符个数
+func ExpandWithCount(sql string, vars []interface{}) (string, int, error) {
+	return literalConfig{}.expand(sql, vars)
+}
+
+// ExpandBatch 生成多行 INSERT：prefix VALUES (row1),(row2),...
+// rowTemplate 是一行括号内的内容，如 "?, ?, ?"，每行参数按 Expand 的规则转义后填入
+// 任意一行的参数个数与占位符个数不符时返回带行号的 error；rows 为空时返回 error
+func ExpandBatch(prefix string, rowTemplate string, rows [][]interface{}) (string, error) {
+	if len(rows) == 0 {
+		return "", fmt.Errorf("ExpandBatch 至少需要一行参数")
+	}
+	var buf strings.Builder
+	buf.WriteString(strings.TrimSpace(prefix))
+	buf.WriteString(" VALUES ")
+	for i, row := range rows {
+		values, _, err := literalConfig{}.expand(rowTemplate, row)
+		if err != nil {
+			return "", fmt.Errorf("第 %d 行: %w", i+1, err)
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('(')
+		buf.WriteString(values)
+		buf.WriteByte(')')
+	}
+	return buf.String(), nil
+}
+
+// ExpandMany 用同一个模板 sql 依次展开 rows 中的每组参数，返回与 rows 一一对应的 SQL
+// 模板只扫描一次，按 ? 占位符切分后每行只做参数转义，适合同一报表模板展开成千上万次的场景；
+// 结果与逐行调用 Expand 相同。任意一行的参数个数与占位符个数不符或参数无法渲染时返回带行号的 error
+func ExpandMany(sql string, rows [][]interface{}) ([]string, error) {
+	c := literalConfig{}
+	var parts []string // 占位符之间的 SQL 片段，比占位符多一个
+	start := 0
+	for pos := nextPlaceholder(sql, 0, c.dialect); pos >= 0; pos = nextPlaceholder(sql, start, c.dialect) {
+		parts = append(parts, unescapeQuestionMarks(sql[start:pos], c.dialect))
+		start = pos + 1
+	}
+	parts = append(parts, unescapeQuestionMarks(sql[start:], c.dialect))
+
+	results := make([]string, len(rows))
+	var buf strings.Builder
+	for i, row := range rows {
+		if len(row) != len(parts)-1 {
+			return nil, fmt.Errorf("第 %d 行: %w", i+1, checkPlaceholderCount(sql, c.dialect, len(row)))
+		}
+		buf.Reset()
+		buf.Grow(len(sql) + 16*len(row))
+		buf.WriteString(parts[0])
+		for j, v := range row {
+			lit, err := c.literal(v)
+			if err != nil {
+				return nil, fmt.Errorf("第 %d 行: %w", i+1, err)
+			}
+			buf.WriteString(lit)
+			buf.WriteString(parts[j+1])
+		}
+		results[i] = buf.String()
+	}
+	return results, nil
+}
+
+// ExpandInChunked 把 values 按每段最多 chunkSize 个元素拆分，返回多个 column IN (...) 片段，
+// 用于超大 IN 列表：调用方可以用 OR 连接这些片段，或分成多条语句执行，避免超出 max_allowed_packet 等限制
+// 元素按 Expand 的规则转义；values 为空时返回单个 column IN (NULL)，与空切片参数的渲染一致
+// 列名的要求同 ExpandUpdate（可带 . 分隔的表名），chunkSize <= 0 时返回 error
+func ExpandInChunked(column string, values []interface{}, chunkSize int) ([]string, error) {
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("chunkSize 必须大于 0，实际为 %d", chunkSize)
+	}
+	for _, part := range strings.Split(column, ".") {
+		if err := checkIdentifier(part); err != nil {
+			return nil, fmt.Errorf("列名 %q: %w", column, err)
+		}
+	}
+	if len(values) == 0 {
+		return []string{column + " IN (NULL)"}, nil
+	}
+
+	chunks := make([]string, 0, (len(values)+chunkSize-1)/chunkSize)
+	var buf strings.Builder
+	for start := 0; start < len(values); start += chunkSize {
+		end := min(start+chunkSize, len(values))
+		buf.Reset()
+		buf.WriteString(column)
+		buf.WriteString(" IN (")
+		for i, v := range values[start:end] {
+			lit, err := literal(v)
+			if err != nil {
+				return nil, fmt.Errorf("第 %d 个元素: %w", start+i+1, err)
+			}
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(lit)
+		}
+		buf.WriteByte(')')
+		chunks = append(chunks, buf.String())
+	}
+	return chunks, nil
+}
+
+// ExpandUpdate 生成 UPDATE table SET k1=v1, k2=v2 WHERE ... 语句
+// 列按名字排序保证输出确定，值按 Expand 的规则转义；where 中的 ? 用 whereArgs 展开，为空时不生成 WHERE
+// 表名和列名只能由 ASCII 字母、数字、下划线组成且不以数字开头（表名可带 . 分隔的库名），否则返回 error
+func ExpandUpdate(table string, set map[string]interface{}, where string, whereArgs []interface{}) (string, error) {
+	if len(set) == 0 {
+		return "", fmt.Errorf("ExpandUpdate 至少需要一个列")
+	}
+	for _, part := range strings.Split(table, ".") {
+		if err := checkIdentifier(part); err != nil {
+			return "", fmt.Errorf("表名 %q: %w", table, err)
+		}
+	}
+	columns := make([]string, 0, len(set))
+	for column := range set {
+		if err := checkIdentifier(column); err != nil {
+			return "", fmt.Errorf("列名 %q: %w", column, err)
+		}
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	var buf strings.Builder
+	buf.WriteString("UPDATE ")
+	buf.WriteString(table)
+	buf.WriteString(" SET ")
+	for i, column := range columns {
+		lit, err := literal(set[column])
+		if err != nil {
+			return "", fmt.Errorf("列 %s: %w", column, err)
+		}
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(column)
+		buf.WriteByte('=')
+		buf.WriteString(lit)
+	}
+	if strings.TrimSpace(where) == "" {
+		if len(whereArgs) > 0 {
+			return "", fmt.Errorf("没有 WHERE 条件但传入了 %d 个参数", len(whereArgs))
+		}
+		return buf.String(), nil
+	}
+	cond, _, err := literalConfig{}.expand(where, whereArgs)
+	if err != nil {
+		return "", err
+	}
+	buf.WriteString(" WHERE ")
+	buf.WriteString(cond)
+	return buf.String(), nil
+}
+
+// ExpandInsertStruct 把结构体 v（或指向结构体的指针）渲染为 INSERT INTO table (列...) VALUES (值...) 语句
+// 列为导出字段，按字段顺序排列：列名取 db 标签（逗号之前的部分），没有标签时使用字段名；标签为 - 的字段跳过
+// 匿名嵌入且没有 db 标签的结构体展开其导出字段（time.Time、driver.Valuer 除外），嵌入类型本身不导出时同样展开，嵌入的 nil 指针跳过；
+// 值按 Expand 的规则转义
+// 表名和列名的要求同 ExpandUpdate，列名重复时返回 error
+func ExpandInsertStruct(table string, v interface{}) (string, error) {
+	for _, part := range strings.Split(table, ".") {
+		if err := checkIdentifier(part); err != nil {
+			return "", fmt.Errorf("表名 %q: %w", table, err)
+		}
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return "", fmt.Errorf("ExpandInsertStruct 不接受 nil 指针")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return "", fmt.Errorf("ExpandInsertStruct 需要结构体，实际为 %T", v)
+	}
+
+	var columns, values []string
+	seen := make(map[string]bool)
+	var walk func(rv reflect.Value) error
+	walk = func(rv reflect.Value) error {
+		for i := 0; i < rv.NumField(); i++ {
+			field, fv := rv.Type().Field(i), rv.Field(i)
+			tag, _, _ := strings.Cut(field.Tag.Get("db"), ",")
+			if tag == "-" {
+				continue
+			}
+			// 与 encoding/json 相同，嵌入的结构体不论类型是否导出都展开，其导出字段照常作为列
+			if field.Anonymous && tag == "" {
+				ev := fv
+				if ev.Kind() == reflect.Ptr {
+					if ev.IsNil() {
+						continue
+					}
+					ev = ev.Elem()
+				}
+				if ev.Kind() == reflect.Struct && !isScalarStruct(ev.Type()) {
+					if err := walk(ev); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+			if !field.IsExported() {
+				continue
+			}
+			column := tag
+			if column == "" {
+				column = field.Name
+			}
+			if err := checkIdentifier(column); err != nil {
+				return fmt.Errorf("列名 %q: %w", column, err)
+			}
+			if seen[column] {
+				return fmt.Errorf("列名 %q 重复", column)
+			}
+			seen[column] = true
+			lit, err := literal(fv.Interface())
+			if err != nil {
+				return fmt.Errorf("列 %s: %w", column, err)
+			}
+			columns = append(columns, column)
+			values = append(values, lit)
+		}
+		return nil
+	}
+	if err := walk(rv); err != nil {
+		return "", err
+	}
+	if len(columns) == 0 {
+		return "", fmt.Errorf("ExpandInsertStruct 至少需要一个列")
+	}
+	return "INSERT INTO " + table + " (" + strings.Join(columns, ", ") + ") VALUES (" + strings.Join(values, ", ") + ")", nil
+}
+
+// SafeOrderBy 把用户提供的排序键转换为可以直接拼接在 ORDER BY 之后的列表，用于无法使用占位符的动态排序
+// cols 中每一项为 "键" 或 "键 方向"，键必须是 allowed 中的键，输出使用对应的值（可信的列表达式）；
+// 方向不区分大小写，只能是 ASC 或 DESC。未知的键返回包装了 ErrNotAllowed 的 error；cols 为空时返回空字符串
+// 例如 SafeOrderBy([]string{"name desc", "age"}, map[string]string{"name": "u.name", "age": "u.age"}) 返回 "u.name DESC, u.age"
+func SafeOrderBy(cols []string, allowed map[string]string) (string, error) {
+	parts := make([]string, 0, len(cols))
+	for _, col := range cols {
+		fields := strings.Fields(col)
+		if len(fields) == 0 || len(fields) > 2 {
+			return "", fmt.Errorf("排序项 %q 应为 \"键\" 或 \"键 方向\"", col)
+		}
+		expr, ok := allowed[fields[0]]
+		if !ok {
+			return "", fmt.Errorf("排序键 %q: %w", fields[0], ErrNotAllowed)
+		}
+		if len(fields) == 2 {
+			dir := strings.ToUpper(fields[1])
+			if dir != "ASC" && dir != "DESC" {
+				return "", fmt.Errorf("排序方向 %q 只能是 ASC 或 DESC", fields[1])
+			}
+			expr += " " + dir
+		}
+		parts = append(parts, expr)
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+// CleanIdentifierList 把用户选择的列名转换为可以直接拼接在 SELECT 之后的列列表，用于动态选择返回列
+// 每个列名必须在 allowed 中且对应的值为 true，否则返回包装了 ErrNotAllowed 的 error；cols 为空时返回 error
+// 列名按方言 d 加引号后以逗号连接，带点号的 t.col 对每一段分别加引号
+// 例如 CleanIdentifierList([]string{"id", "u.name"}, allowed, DialectMySQL) 返回 "`id`, `u`.`name`"
+func CleanIdentifierList(cols []string, allowed map[string]bool, d Dialect) (string, error) {
+	if len(cols) == 0 {
+		return "", fmt.Errorf("列列表不能为空")
+	}
+	parts := make([]string, 0, len(cols))
+	for _, col := range cols {
+		if !allowed[col] {
+			return "", fmt.Errorf("列 %q: %w", col, ErrNotAllowed)
+		}
+		segments := strings.Split(col, ".")
+		for i, seg := range segments {
+			if seg == "" {
+				return "", fmt.Errorf("列 %q 含有空的标识符", col)
+			}
+			segments[i] = d.quoteIdentifier(seg)
+		}
+		parts = append(parts, strings.Join(segments, "."))
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+// isScalarStruct 判断结构体类型 t 是否作为单个值渲染（time.Time、driver.Valuer），而不是展开其字段
+func isScalarStruct(t reflect.Type) bool {
+	return t == reflect.TypeOf(time.Time{}) || t.Implements(reflect.TypeOf((*driver.Valuer)(nil)).Elem())
+}
+
+// checkIdentifier 检查 name 是否为可以不加引号直接写入 SQL 的非空标识符：
+// 只含 ASCII 字母、数字和下划线，且不以数字开头。标识符不加引号输出，因此不能复用 isIDRune，
+// 否则 id-- 这样的名字会在 MySQL 中开启注释
+func checkIdentifier(name string) error {
+	if name == "" {
+		return fmt.Errorf("标识符不能为空")
+	}
+	for i, r := range name {
+		if !isIdentifierRune(r) || (i == 0 && r >= '0' && r <= '9') {
+			return &InjectionError{Type: ParamTypeID, Pattern: string(r), Offset: i}
+		}
+	}
+	return nil
+}
+
+// isIdentifierRune 判断是否为未加引号的标识符允许的字符：ASCII 字母、数字、下划线
+func isIdentifierRune(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_'
+}
+
+// expand 展开 SQL，返回结果和已替换的占位符个数
+func (c literalConfig) expand(sql string, vars []interface{}) (string, int, error) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer putBuffer(buf)
+	n, err := c.expandTo(buf, sql, vars)
+	if err != nil {
+		return "", n, err
+	}
+	return buf.String(), n, nil // String 复制内容，返回后缓冲区可以安全复用
+}
+
+// bufferPool 复用 expand 的输出缓冲区，减少高并发下的内存分配
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// maxPooledBuffer 超过该容量的缓冲区不放回池中，避免偶发的超大 SQL 长期占用内存
+const maxPooledBuffer = 64 << 10
+
+// putBuffer 清空 buf 并放回池中
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// ExpandTo 与 Expand 相同，但边扫描边把结果写入 w，不在内存中拼接整条 SQL，适合生成很大的脚本
+// 写入前先检查占位符个数与参数个数是否一致；之后出现不支持的类型或 w 写入失败时，
+// w 中可能已写入部分内容
+func ExpandTo(w io.Writer, sql string, vars []interface{}) error {
+	c := literalConfig{}
+	if err := checkPlaceholderCount(sql, c.dialect, len(vars)); err != nil {
+		return err
+	}
+	_, err := c.expandTo(w, sql, vars)
+	return err
+}
+
+// expandTo 展开 SQL 并写入 w，返回已替换的占位符个数
+func (c literalConfig) expandTo(w io.Writer, sql string, vars []interface{}) (int, error) {
+	if err := c.checkArgCount(vars); err != nil {
+		return 0, err
+	}
+	var (
+		argI  = 0
+		start int
+	)
+	for pos := nextPlaceholder(sql, start, c.dialect); pos >= 0; pos = nextPlaceholder(sql, start, c.dialect) {
+		if err := c.checkContext(argI); err != nil {
+			return argI, err
+		}
+		if argI >= len(vars) {
+			return argI, newExpandError("占位符个数 > 参数个数", sql, c.dialect, len(vars), pos)
+		}
+		lit, err := c.literal(vars[argI]) // 转义值
+		if err != nil {
+			return argI, err
+		}
+		if _, err := io.WriteString(w, unescapeQuestionMarks(sql[start:pos], c.dialect)); err != nil { // 复制到 ? 之前
+			return argI, err
+		}
+		if _, err := io.WriteString(w, lit); err != nil {
+			return argI, err
+		}
+		start = pos + 1 // 跳过已处理部分
+		argI++
+	}
+	if argI != len(vars) {
+		return argI, newExpandError("占位符个数 < 参数个数", sql, c.dialect, len(vars), -1)
+	}
+	_, err := io.WriteString(w, unescapeQuestionMarks(sql[start:], c.dialect))
+	return argI, err
+}
 
-	// 3. 检测和替换常见SQL注入关键字模式
-	dangerousPatterns := map[string]string{
-		"union select":      "union_select",
-		"union all select":  "union_all_select",
-		"'; drop table":     "'; drop_table",
-		"'; delete from":    "'; delete_from",
-		"'; truncate table": "'; truncate_table",
-		"'; insert into":    "'; insert_into",
-		"'; update ":        "'; update_",
-		"; drop table":      "; drop_table",
-		"; delete from":     "; delete_from",
-		"; truncate table":  "; truncate_table", 
-		"; insert into":     "; insert_into",
-		"; update ":         "; update_",
-		" or 1=1":           "_or_1=1",
-		" or '1'='1":        "_or_'1'='1",
-		" and 1=1":          "_and_1=1",
-		"/*":                "/_*",
-		"*/":                "*_/",
-		"--":                "__",
-		"xp_cmdshell":       "xp_cmd_shell",
-		"sp_executesql":     "sp_execute_sql",
-	}
-
-	// 转为小写进行检测，但保持原始大小写进行替换
-	lower := strings.ToLower(normalized)
-	result := normalized
-
-	for pattern, replacement := range dangerousPatterns {
-		if strings.Contains(lower, pattern) {
-			result = replaceCaseInsensitive(result, pattern, replacement)
-			lower = strings.ToLower(result) // 更新小写版本用于下一次检查
+// checkPlaceholderCount 检查占位符个数与参数个数是否一致，不一致时返回与 expand 相同的 *ExpandError
+func checkPlaceholderCount(sql string, d Dialect, args int) error {
+	n := 0
+	for pos := nextPlaceholder(sql, 0, d); pos >= 0; pos = nextPlaceholder(sql, pos+1, d) {
+		if n == args {
+			return newExpandError("占位符个数 > 参数个数", sql, d, args, pos)
 		}
+		n++
 	}
-
-	// 4. 长度限制
-	if len(result) > 2000 {
-		result = result[:2000]
+	if n != args {
+		return newExpandError("占位符个数 < 参数个数", sql, d, args, -1)
 	}
+	return nil
+}
 
-	return result
+// ExpandError 占位符与参数个数不匹配时 Expand 返回的错误，可通过 errors.As 获取
+type ExpandError struct {
+	Placeholders int    // SQL 中的占位符个数
+	Args         int    // 传入的参数个数
+	Offset       int    // 第一个没有对应参数的 ? 的字节偏移，参数过多时为 -1
+	Msg          string // 错误描述
 }
 
-// NameValidator 名称类型验证器，支持中文，检测SQL注入关键字
-type NameValidator struct{}
+func (e *ExpandError) Error() string {
+	if e.Offset >= 0 {
+		return fmt.Sprintf("%s：占位符 %d 个，参数 %d 个，位置 %d", e.Msg, e.Placeholders, e.Args, e.Offset)
+	}
+	return fmt.Sprintf("%s：占位符 %d 个，参数 %d 个", e.Msg, e.Placeholders, e.Args)
+}
 
-func (v NameValidator) GetType() ParamType {
-	return ParamTypeName
+func newExpandError(msg, sql string, d Dialect, args, offset int) *ExpandError {
+	return &ExpandError{
+		Placeholders: countPlaceholders(sql, d),
+		Args:         args,
+		Offset:       offset,
+		Msg:          msg,
+	}
 }
 
-func (v NameValidator) Validate(value string) string {
-	// 1. Unicode规范化，将全角字符转换为半角
-	normalized := norm.NFKC.String(value)
+// TooManyArgsError 参数值总个数超过 WithMaxArgs 设置的上限时返回的错误，可通过 errors.As 获取
+type TooManyArgsError struct {
+	Limit int // WithMaxArgs 设置的上限
+	Count int // 实际的参数值个数，切片按元素个数计数
+}
 
-	// 2. 统一空白符处理
-	normalized = strings.ReplaceAll(normalized, "\t", " ")
-	normalized = strings.ReplaceAll(normalized, "\n", " ")
-	normalized = strings.ReplaceAll(normalized, "\r", " ")
-	// 合并多个连续空格为单个空格
-	normalized = regexp.MustCompile(`\s+`).ReplaceAllString(normalized, " ")
-	normalized = strings.TrimSpace(normalized)
+func (e *TooManyArgsError) Error() string {
+	return fmt.Sprintf("参数值个数 %d 超过上限 %d", e.Count, e.Limit)
+}
 
-	// 3. 检测和替换危险SQL关键字模式
-	dangerousPatterns := map[string]string{
-		"union select":     "union_select",
-		"union all select": "union_all_select",
-		" or ":             "_or_",
-		" and ":            "_and_",
-		"' or '":           "'_or_'",
-		"\" or \"":         "\"_or_\"",
-		"' and '":          "'_and_'",
-		"\" and \"":        "\"_and_\"",
-		" or 1=1":          "_or_1=1",
-		" or '1'='1":       "_or_'1'='1",
-		"'; drop table":    "'; drop_table",
-		"'; delete from":   "'; delete_from",
-		"'; insert into":   "'; insert_into",
-		"'; update set":    "'; update_set",
-		"/*":               "/_*",
-		"*/":               "*_/",
-		"--":               "__",
-		"#":                "_#",
-		"xp_cmdshell":      "xp_cmd_shell",
-		"sp_executesql":    "sp_execute_sql",
-		"ascii":            "_ascii_",
-		"substring":        "_substring_",
-		"concat":           "_concat_",
-		"extractvalue":     "_extractvalue_",
-		"waitfor":          "_waitfor_",
-		"delay":            "_delay_",
-	}
-
-	// 转为小写进行检测，但保持原始大小写进行替换
-	lower := strings.ToLower(normalized)
-	result := normalized
-
-	for pattern, replacement := range dangerousPatterns {
-		if strings.Contains(lower, pattern) {
-			result = replaceCaseInsensitive(result, pattern, replacement)
-			lower = strings.ToLower(result) // 更新小写版本用于下一次检查
-		}
+// checkArgCount 检查 vars 中参数值的总个数是否超过 maxArgs
+func (c literalConfig) checkArgCount(vars []interface{}) error {
+	if c.maxArgs <= 0 {
+		return nil
 	}
-
-	// 4. 长度限制
-	if len(result) > 500 {
-		result = result[:500]
+	n := 0
+	for _, v := range vars {
+		n += argCount(v)
 	}
-
-	return result
+	if n > c.maxArgs {
+		return &TooManyArgsError{Limit: c.maxArgs, Count: n}
+	}
+	return nil
 }
 
-// TypeAwareProcessor 类型感知处理器管理器
-type TypeAwareProcessor struct {
-	validators map[ParamType]ParamValidator
+// argCount 返回 v 渲染出的参数值个数：切片和 TypedList 按元素个数计数，字节切片等其他值计为 1
+func argCount(v interface{}) int {
+	switch val := v.(type) {
+	case TypedList:
+		return len(val.Values)
+	case driver.Valuer:
+		return 1
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+		return rv.Len()
+	}
+	return 1
 }
 
-// NewTypeAwareProcessor 创建类型感知处理器
-func NewTypeAwareProcessor() *TypeAwareProcessor {
-	processor := &TypeAwareProcessor{
-		validators: make(map[ParamType]ParamValidator),
-	}
-	
-	// 注册所有验证器
-	processor.RegisterValidator(IDValidator{})
-	processor.RegisterValidator(NameValidator{})
-	processor.RegisterValidator(DescriptionValidator{})
-	processor.RegisterValidator(GenericValidator{})
-	
-	return processor
+// Literal 把 Go 值转成 SQL 字面量（导出版本用于测试）
+func Literal(v interface{}) (string, error) {
+	return defaultSanitizer.Literal(v)
 }
 
-// RegisterValidator 注册验证器
-func (tap *TypeAwareProcessor) RegisterValidator(validator ParamValidator) {
-	tap.validators[validator.GetType()] = validator
+// InspectString 返回字符串参数在 Expand 中被推断出的类型和清理后的结果（未加引号），用于排查误判
+func InspectString(value string) (ParamType, string) {
+	return inspectString(value)
 }
 
-// GetValidator 获取指定类型的验证器
-func (tap *TypeAwareProcessor) GetValidator(paramType ParamType) ParamValidator {
-	if validator, exists := tap.validators[paramType]; exists {
-		return validator
-	}
-	// 默认返回通用验证器
-	return tap.validators[ParamTypeGeneric]
+// inspectString 用全局推断器推断 s 的类型，再用全局处理器清理
+func inspectString(s string) (ParamType, string) {
+	return literalConfig{}.inspect(s)
 }
 
-// ProcessString 处理字符串参数，使用指定类型的验证器
-func (tap *TypeAwareProcessor) ProcessString(value string, paramType ParamType) string {
-	validator := tap.GetValidator(paramType)
-	return validator.Validate(value)
+// inspect 推断字符串的类型并用对应的验证器清理
+func (c literalConfig) inspect(s string) (ParamType, string) {
+	processor, inferrer := c.pipeline()
+	paramType := inferrer.InferType(s)
+	return paramType, processor.ProcessString(s, paramType)
 }
 
-// 全局类型感知处理器实例
-var globalProcessor = NewTypeAwareProcessor()
+// 常用的时间格式
+const (
+	DefaultTimeLayout = "2006-01-02 15:04:05"        // 精确到秒
+	TimeLayoutMicro   = "2006-01-02 15:04:05.999999" // 精确到微秒，省略末尾的 0
+)
 
-// TypeInferrer 类型推断器，根据字符串内容推断参数类型
-type TypeInferrer struct{}
+// 时间参数的渲染选项，影响所有调用方，应在程序启动时设置
+var (
+	// TimeLayout time.Time 的格式化布局
+	TimeLayout = DefaultTimeLayout
+	// TimeInUTC 为 true 时先把时间转换为 UTC 再格式化
+	TimeInUTC = false
+	// ZeroTimeAsNull 为 true 时 time.Time 零值（包括指向零值的 *time.Time）渲染为 NULL，
+	// 避免 MySQL 对部分列类型拒绝 '0001-01-01 00:00:00'；nil 的 *time.Time 总是渲染为 NULL
+	ZeroTimeAsNull = false
+	// DurationUnit time.Duration 渲染为该单位的整数个数（四舍五入），如 time.Millisecond
+	DurationUnit = time.Second
+)
 
-// InferType 推断参数类型
-func (ti *TypeInferrer) InferType(value string) ParamType {
-	// 长度检查优先级最高
-	if len(value) == 0 {
-		return ParamTypeGeneric
-	}
+// ErrUnsupportedType Literal、Expand 等遇到无法渲染为 SQL 字面量的类型时返回的错误（经过包装，用 errors.Is 判断）
+var ErrUnsupportedType = errors.New("unsupported type")
 
-	// ID类型检测：纯字母数字组合，通常较短
-	if len(value) <= 100 {
-		isID := true
-		for _, r := range value {
-			if !((r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || 
-			     (r >= '0' && r <= '9') || r == '-' || r == '_') {
-				isID = false
-				break
-			}
-		}
-		if isID {
-			return ParamTypeID
-		}
-	}
+// ErrNonFiniteFloat 浮点参数为 NaN 或 ±Inf 时 Literal、Expand 等返回的错误，可通过 errors.Is 判断
+var ErrNonFiniteFloat = errors.New("NaN 和 Inf 不是合法的 SQL 数值")
 
-	// 描述类型检测：长度超过500或包含换行符
-	if len(value) > 500 || strings.Contains(value, "\n") || strings.Contains(value, "\r") {
-		return ParamTypeDescription
+// unsupportedTypeError 返回包含实际类型和修改建议的 ErrUnsupportedType
+func unsupportedTypeError(rv reflect.Value) error {
+	typ := rv.Type().String()
+	if kind := rv.Kind().String(); kind != typ {
+		typ += "（底层类型 " + kind + "）"
 	}
+	return fmt.Errorf("%w %s：请先转换为 string、整数、浮点数、bool、[]byte、time.Time 等受支持的类型，"+
+		"或为该类型实现 driver.Valuer 或 fmt.Stringer", ErrUnsupportedType, typ)
+}
 
-	// 名称类型检测：包含中文字符或常见名称模式
-	hasChineseOrNamePattern := false
-	for _, r := range value {
-		// 检测中文字符范围
-		if (r >= 0x4e00 && r <= 0x9fff) || // 中文基本汉字
-		   (r >= 0x3400 && r <= 0x4dbf) || // 中文扩展A
-		   (r >= 0xf900 && r <= 0xfaff) {  // 中文兼容汉字
-			hasChineseOrNamePattern = true
-			break
-		}
-	}
-	
-	// 检测常见名称模式
-	if !hasChineseOrNamePattern {
-		namePatterns := []string{"项目", "小区", "大厦", "广场", "中心", "花园", "公寓", "别墅", "期", "区", "号"}
-		for _, pattern := range namePatterns {
-			if strings.Contains(value, pattern) {
-				hasChineseOrNamePattern = true
-				break
-			}
-		}
-	}
-	
-	if hasChineseOrNamePattern {
-		return ParamTypeName
-	}
+// supportedTypes literal 的类型分支直接处理的类型，顺序与分支一致；测试会检查两者保持同步
+var supportedTypes = []reflect.Type{
+	reflect.TypeOf(false),
+	reflect.TypeOf(int(0)), reflect.TypeOf(int8(0)), reflect.TypeOf(int16(0)), reflect.TypeOf(int32(0)), reflect.TypeOf(int64(0)),
+	reflect.TypeOf(uint(0)), reflect.TypeOf(uint8(0)), reflect.TypeOf(uint16(0)), reflect.TypeOf(uint32(0)), reflect.TypeOf(uint64(0)),
+	reflect.TypeOf(float32(0)), reflect.TypeOf(float64(0)),
+	reflect.TypeOf(""),
+	reflect.TypeOf([]byte(nil)),
+	reflect.TypeOf(HexBytes(nil)),
+	reflect.TypeOf(BinaryBytes(nil)),
+	reflect.TypeOf(TypedList{}),
+	reflect.TypeOf(Numeric("")),
+	reflect.TypeOf(json.RawMessage(nil)),
+	reflect.TypeOf(JSONValue(nil)),
+	reflect.TypeOf(time.Time{}),
+	reflect.TypeOf(time.Duration(0)),
+	reflect.TypeOf((*big.Int)(nil)),
+	reflect.TypeOf((*big.Float)(nil)),
+	reflect.TypeOf(sql.NullString{}),
+	reflect.TypeOf(sql.NullInt64{}),
+	reflect.TypeOf(sql.NullInt32{}),
+	reflect.TypeOf(sql.NullInt16{}),
+	reflect.TypeOf(sql.NullByte{}),
+	reflect.TypeOf(sql.NullFloat64{}),
+	reflect.TypeOf(sql.NullBool{}),
+	reflect.TypeOf(sql.NullTime{}),
+}
 
-	// 默认返回通用类型
-	return ParamTypeGeneric
+// SupportedTypes 返回 Literal、Expand 等直接支持的 Go 类型，可用于生成文档或在调用 Expand 之前预先检查参数
+// 除此之外还支持：nil，实现了 driver.Valuer 或 fmt.Stringer 的类型，指向受支持类型的指针，
+// 元素为受支持类型的切片（展开为 IN 列表），以及 RegisterEnumMapper 认领的类型
+// 返回的切片是副本，调用方可以修改
+func SupportedTypes() []reflect.Type {
+	return append([]reflect.Type(nil), supportedTypes...)
 }
 
-// 全局类型推断器实例
-var globalInferrer = &TypeInferrer{}
+// literal 把 Go 值转成 SQL 字面量，使用默认的 MySQL 方言
+func literal(v interface{}) (string, error) {
+	return literalConfig{}.literal(v)
+}
 
-// Expand 把带 ? 占位符的 SQL 展开成可直接执行的纯文本 SQL
-// 如果占位符数量与参数个数不符，或出现未知类型，返回 error
-func Expand(sql string, vars []interface{}) (string, error) {
-	var (
-		buf   strings.Builder
-		argI  = 0
-		start int
-	)
-	for pos := strings.IndexByte(sql[start:], '?'); pos >= 0; pos = strings.IndexByte(sql[start:], '?') {
-		if argI >= len(vars) {
-			return "", errors.New("占位符个数 > 参数个数")
+// literalConfig 控制 Go 值渲染为 SQL 字面量的方式，零值即默认行为
+type literalConfig struct {
+	dialect   Dialect
+	ctx       context.Context     // 非 nil 时展开过程中定期检查是否已取消
+	processor *TypeAwareProcessor // 为 nil 时使用全局处理器
+	inferrer  *TypeInferrer       // 为 nil 时使用全局推断器
+
+	strict     bool   // 严格模式，字符串含危险模式时返回 error 而不是改写
+	timeLayout string // time.Time 的格式化布局，为空时使用 TimeLayout
+	boolAsInt  bool   // bool 渲染为 1/0 而不是 true/false
+	maxArgs    int    // 参数值总个数的上限，<= 0 表示不限制
+	fixedFloat bool   // 浮点数使用定点格式，不使用科学计数法
+	nanAsNull  bool   // NaN 和 ±Inf 渲染为 NULL 而不是返回 error
+}
+
+// pipeline 返回渲染字符串时使用的处理器和推断器，未指定的使用全局实例
+func (c literalConfig) pipeline() (*TypeAwareProcessor, *TypeInferrer) {
+	processor, inferrer := c.processor, c.inferrer
+	if processor == nil || inferrer == nil {
+		globalProcessor, globalInferrer := globalPipeline()
+		if processor == nil {
+			processor = globalProcessor
 		}
-		pos += start
-		buf.WriteString(sql[:pos])      // 复制到 ? 之前
-		lit, err := literal(vars[argI]) // 转义值
-		if err != nil {
-			return "", err
+		if inferrer == nil {
+			inferrer = globalInferrer
 		}
-		buf.WriteString(lit)
-		sql = sql[pos+1:] // 去掉已处理部分
-		start = 0
-		argI++
-	}
-	if argI != len(vars) {
-		return "", errors.New("占位符个数 < 参数个数")
 	}
-	buf.WriteString(sql)
-	return buf.String(), nil
-}
-
-// Literal 把 Go 值转成 SQL 字面量（导出版本用于测试）
-func Literal(v interface{}) (string, error) {
-	return literal(v)
+	return processor, inferrer
 }
 
 // literal 把 Go 值转成 SQL 字面量
-func literal(v interface{}) (string, error) {
+// 先尝试 RegisterEnumMapper 注册的映射函数；内置类型之外的值依次尝试：nil 指针渲染为 NULL、driver.Valuer、指针解引用、切片展开、
+// fmt.Stringer，都不满足时返回 error
+func (c literalConfig) literal(v interface{}) (string, error) {
+	if s, ok := mapEnum(v); ok {
+		return c.stringLiteral(s)
+	}
 	switch val := v.(type) {
 	case nil:
 		return "NULL", nil
 	case bool:
+		if c.boolAsInt || c.dialect.boolAsInt() {
+			if val {
+				return "1", nil
+			}
+			return "0", nil
+		}
 		return strconv.FormatBool(val), nil
 	case int, int8, int16, int32, int64,
 		uint, uint8, uint16, uint32, uint64:
 		return fmt.Sprintf("%d", val), nil
 	case float32, float64:
-		return strconv.FormatFloat(
-			reflectFloat(val), 'g', -1, 64), nil
+		return c.floatLiteral(reflectFloat(val))
 	case string:
-		// 使用类型感知验证进行字符串清理
-		paramType := globalInferrer.InferType(val)
-		sanitized := globalProcessor.ProcessString(val, paramType)
-		return quoteString(sanitized), nil
+		return c.stringLiteral(val)
 	case []byte:
-		str := string(val)
-		// 使用类型感知验证进行字符串清理
-		paramType := globalInferrer.InferType(str)
-		sanitized := globalProcessor.ProcessString(str, paramType)
-		return quoteString(sanitized), nil
+		// 按二进制数据处理：只按字节转义引号，不做规范化和危险模式清理，避免破坏图片、压缩数据等
+		if c.dialect.rejectsNUL() && bytes.IndexByte(val, 0) >= 0 {
+			return "", fmt.Errorf("%v 的文本字面量不能包含空字节，二进制数据请使用 HexBytes", c.dialect)
+		}
+		return QuoteStringFor(string(val), c.dialect), nil
+	case HexBytes:
+		return HexLiteralFor(val, c.dialect), nil
+	case BinaryBytes:
+		return binaryLiteralFor(val, c.dialect), nil
+	case TypedList:
+		return c.typedListLiteral(val)
+	case Numeric:
+		return numericLiteral(val)
+	case json.RawMessage:
+		return c.jsonLiteral(string(val))
+	case JSONValue:
+		if val == nil {
+			return "NULL", nil
+		}
+		b, err := json.Marshal(map[string]interface{}(val))
+		if err != nil {
+			return "", fmt.Errorf("JSONValue: %w", err)
+		}
+		return c.jsonLiteral(string(b))
 	case time.Time:
-		return fmt.Sprintf("'%s'", val.Format("2006-01-02 15:04:05")), nil
+		return c.timeLiteral(val), nil
+	case time.Duration:
+		return strconv.FormatInt(durationCount(val, DurationUnit), 10), nil
+	case *big.Int:
+		if val == nil {
+			return "NULL", nil
+		}
+		return val.String(), nil
+	case *big.Float:
+		if val == nil {
+			return "NULL", nil
+		}
+		if val.IsInf() {
+			f, _ := val.Float64() // ±Inf，与 float64 一样按 ErrNonFiniteFloat、WithNaNAsNull 处理
+			return c.floatLiteral(f)
+		}
+		// 使用定点格式保留全部精度，避免 MySQL 误读科学计数法
+		return val.Text('f', -1), nil
+	case sql.NullString:
+		if !val.Valid {
+			return "NULL", nil
+		}
+		return c.literal(val.String)
+	case sql.NullInt64:
+		if !val.Valid {
+			return "NULL", nil
+		}
+		return c.literal(val.Int64)
+	case sql.NullInt32:
+		if !val.Valid {
+			return "NULL", nil
+		}
+		return c.literal(val.Int32)
+	case sql.NullInt16:
+		if !val.Valid {
+			return "NULL", nil
+		}
+		return c.literal(val.Int16)
+	case sql.NullByte:
+		if !val.Valid {
+			return "NULL", nil
+		}
+		return c.literal(val.Byte)
+	case sql.NullFloat64:
+		if !val.Valid {
+			return "NULL", nil
+		}
+		return c.literal(val.Float64)
+	case sql.NullBool:
+		if !val.Valid {
+			return "NULL", nil
+		}
+		return c.literal(val.Bool)
+	case sql.NullTime:
+		if !val.Valid {
+			return "NULL", nil
+		}
+		return c.literal(val.Time)
 	default:
+		rv := reflect.ValueOf(val)
+		// nil 指针渲染为 NULL，须在 Valuer 之前判断，避免以 nil 接收者调用 Value
+		if rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return "NULL", nil
+		}
 		// 处理 driver.Valuer
 		if vv, ok := val.(driver.Valuer); ok {
 			dv, err := vv.Value()
 			if err != nil {
+				return "", fmt.Errorf("%T.Value: %w", val, err)
+			}
+			// 返回值按同一配置（方言、时间格式等）渲染
+			return c.literal(dv)
+		}
+		switch rv.Kind() {
+		case reflect.Ptr:
+			// 指针接收者的 String 方法（如 *url.URL、*big.Rat）解引用后会丢失，须先判断；
+			// 值接收者的 String 方法解引用后仍可用，先按指向的值处理，使 *time.Time 等照常渲染
+			if s, ok := val.(fmt.Stringer); ok && !rv.Type().Elem().Implements(reflect.TypeOf((*fmt.Stringer)(nil)).Elem()) {
+				return c.stringLiteral(s.String())
+			}
+			// 处理指针，解引用后按指向的值处理
+			return c.literal(rv.Elem().Interface())
+		case reflect.Slice:
+			// 处理切片，展开成 IN 子句的值列表
+			return c.sliceLiteral(rv)
+		}
+		// 其他类型（如 uuid.UUID、带 String 方法的枚举）按 String() 的结果作为字符串处理
+		if s, ok := val.(fmt.Stringer); ok {
+			return c.stringLiteral(s.String())
+		}
+		return "", unsupportedTypeError(rv)
+	}
+}
+
+// sliceLiteral 把切片展开成逗号分隔的字面量列表，用于 IN (?) 子句
+// 空切片渲染为 NULL，使 IN (NULL) 保持语法合法；元素类型为字节的切片按 []byte 处理
+func (c literalConfig) sliceLiteral(rv reflect.Value) (string, error) {
+	if rv.Type().Elem().Kind() == reflect.Uint8 {
+		return c.literal(rv.Bytes())
+	}
+	if rv.Len() == 0 {
+		return "NULL", nil
+	}
+	var buf strings.Builder
+	for i := 0; i < rv.Len(); i++ {
+		if i > 0 {
+			// 单个 IN (?) 的大切片同样响应取消，第 0 个元素之前展开循环已经检查过
+			if err := c.checkContext(i); err != nil {
+				return "", err
+			}
+			buf.WriteByte(',')
+		}
+		lit, err := c.literal(rv.Index(i).Interface())
+		if err != nil {
+			return "", err
+		}
+		buf.WriteString(lit)
+	}
+	return buf.String(), nil
+}
+
+// stringLiteral 使用类型感知验证清理字符串后按方言加引号
+func (c literalConfig) stringLiteral(s string) (string, error) {
+	processor, inferrer := c.pipeline()
+	sanitized, err := c.process(processor, s, inferrer.InferType(s))
+	if err != nil {
+		return "", err
+	}
+	return QuoteStringFor(sanitized, c.dialect), nil
+}
+
+// jsonLiteral 用 JSON 验证器处理 JSON 文本后按方言加引号，合法的 JSON 原样保留，只做引号转义
+func (c literalConfig) jsonLiteral(s string) (string, error) {
+	processor, _ := c.pipeline()
+	sanitized, err := c.process(processor, s, ParamTypeJSON)
+	if err != nil {
+		return "", err
+	}
+	return QuoteStringFor(sanitized, c.dialect), nil
+}
+
+// process 用 processor 按 paramType 清理字符串，严格模式下检测到危险输入时返回 error
+func (c literalConfig) process(processor *TypeAwareProcessor, s string, paramType ParamType) (string, error) {
+	if c.strict {
+		return processor.ProcessStringStrict(s, paramType)
+	}
+	return processor.ProcessString(s, paramType), nil
+}
+
+// typedListLiteral 用 l.Type 对应的验证器清理每个元素，渲染为逗号分隔的字符串字面量列表
+func (c literalConfig) typedListLiteral(l TypedList) (string, error) {
+	if len(l.Values) == 0 {
+		return "NULL", nil
+	}
+	processor, _ := c.pipeline()
+	var buf strings.Builder
+	for i, v := range l.Values {
+		if i > 0 {
+			if err := c.checkContext(i); err != nil {
 				return "", err
 			}
-			return literal(dv)
+			buf.WriteByte(',')
+		}
+		sanitized, err := c.process(processor, v, l.Type)
+		if err != nil {
+			return "", err
+		}
+		buf.WriteString(QuoteStringFor(sanitized, c.dialect))
+	}
+	return buf.String(), nil
+}
+
+// timeLiteral 按 TimeLayout（或 WithTimeLayout 指定的布局）、TimeInUTC、ZeroTimeAsNull 渲染时间
+func (c literalConfig) timeLiteral(t time.Time) string {
+	if ZeroTimeAsNull && t.IsZero() {
+		return "NULL"
+	}
+	if TimeInUTC {
+		t = t.UTC()
+	}
+	layout := c.timeLayout
+	if layout == "" {
+		layout = TimeLayout
+	}
+	return QuoteStringFor(t.Format(layout), c.dialect)
+}
+
+// durationCount 返回 d 中包含多少个 unit，四舍五入到整数；unit 非正数时按纳秒计
+func durationCount(d, unit time.Duration) int64 {
+	if unit <= 0 {
+		unit = time.Nanosecond
+	}
+	return int64(d.Round(unit) / unit)
+}
+
+// floatLiteral 渲染浮点数：默认使用最短的 'g' 格式，很大或很小的值会使用科学计数法（如 1e+21）；
+// 开启 WithFixedPointFloats 时使用定点格式（如 1000000000000000000000）
+// NaN 和 ±Inf 没有对应的 SQL 字面量，返回 ErrNonFiniteFloat；开启 WithNaNAsNull 时渲染为 NULL
+func (c literalConfig) floatLiteral(f float64) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		if c.nanAsNull {
+			return "NULL", nil
 		}
-		return "", fmt.Errorf("unsupported type %T", val)
+		return "", fmt.Errorf("%w: %v", ErrNonFiniteFloat, f)
 	}
+	if c.fixedFloat {
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64), nil
 }
 
 func reflectFloat(v interface{}) float64 {
@@ -448,68 +2390,234 @@ func sanitizeStringInput(s string) string {
 	}
 
 	// 检测并替换常见的SQL注入关键字组合
-	dangerousPatterns := map[string]string{
-		"union select":     "union_select",
-		"union all select": "union_all_select",
-		"' or '1'='1":      "'_or_'1'='1",
-		"' or 1=1":         "'_or_1=1",
-		"'; drop table":    "';_drop_table",
-		"'; delete from":   "';_delete_from",
-		"'; update ":       "';_update_",
-		"'; insert into":   "';_insert_into",
-		"/*":               "/_*",
-		"*/":               "*_/",
-		"--":               "__",
-		"xp_cmdshell":      "xp_cmd_shell",
-		"sp_executesql":    "sp_execute_sql",
-	}
-
-	// 将字符串转换为小写进行匹配，但保持原始大小写进行替换
-	lower := strings.ToLower(s)
-	result := s
-
-	for pattern, replacement := range dangerousPatterns {
-		if strings.Contains(lower, pattern) {
-			// 使用大小写不敏感的替换
-			result = replaceCaseInsensitive(result, pattern, replacement)
-			lower = strings.ToLower(result) // 更新小写版本用于下一次检查
-		}
-	}
+	return applyPatterns(s, legacyPatterns, trace{})
+}
 
-	return result
+// legacyPatterns sanitizeStringInput 使用的危险模式，按顺序应用
+var legacyPatterns = []PatternRule{
+	{"'; drop table", "';_drop_table"},
+	{"'; delete from", "';_delete_from"},
+	{"'; update ", "';_update_"},
+	{"'; insert into", "';_insert_into"},
+	{"union all select", "union_all_select"},
+	{"union select", "union_select"},
+	{"' or '1'='1", "'_or_'1'='1"},
+	{"' or 1=1", "'_or_1=1"},
+	{"/*", "/_*"},
+	{"*/", "*_/"},
+	{"--", "__"},
+	{"xp_cmdshell", "xp_cmd_shell"},
+	{"sp_executesql", "sp_execute_sql"},
 }
 
-// replaceCaseInsensitive 执行大小写不敏感的字符串替换
+// replaceCaseInsensitive 执行大小写不敏感的字符串替换，按 Unicode 大小写折叠比较（见 foldCase）
 func replaceCaseInsensitive(s, old, new string) string {
-	// 使用正则表达式进行大小写不敏感替换
-	oldLower := strings.ToLower(old)
-	sLower := strings.ToLower(s)
+	f, _ := newFoldedString(s).replace(foldCase(old), new, false)
+	return f.s
+}
 
-	// 找到所有匹配位置
-	var result strings.Builder
-	lastEnd := 0
+// foldedString 字符串及其折叠形式，替换时同步更新折叠形式，避免每次替换后重新折叠整个字符串
+type foldedString struct {
+	s       string
+	lower   string // foldCase(s)
+	offsets []int  // 同 foldWithOffsets，为 nil 时 s 与 lower 的偏移一一对应
+}
 
-	for {
-		index := strings.Index(sLower[lastEnd:], oldLower)
-		if index == -1 {
-			break
-		}
+func newFoldedString(s string) foldedString {
+	lower, offsets := foldWithOffsets(s)
+	return foldedString{s: s, lower: lower, offsets: offsets}
+}
+
+// replace 把 f 中所有折叠形式等于 old（须已折叠）的部分替换为 new，返回替换后的结果和替换次数
+// 折叠形式的长度可能与原串不同（如 ß、İ、非法 UTF-8），匹配位置需映射回原串；
+// keepCase 为 true 时每处替换的 new 按 matchCase 沿用被替换部分的大小写
+func (f foldedString) replace(old, new string, keepCase bool) (foldedString, int) {
+	index := strings.Index(f.lower, old)
+	if index < 0 {
+		return f, 0
+	}
 
+	b := foldedBuilder{track: f.offsets != nil || !isASCII(new)}
+	b.s.Grow(len(f.s))
+	b.lower.Grow(len(f.lower))
+	if b.track {
+		b.offsets = make([]int, 0, len(f.lower)+1)
+	}
+	n, lastEnd := 0, 0 // lastEnd 为折叠形式中的位置
+	for ; index >= 0; index = strings.Index(f.lower[lastEnd:], old) {
 		// 添加匹配前的部分
 		actualIndex := lastEnd + index
-		result.WriteString(s[lastEnd:actualIndex])
+		from := endOffsetIn(f.offsets, lastEnd)
+		to := offsetIn(f.offsets, actualIndex)
+		if to > from {
+			b.write(f.s[from:to], f.lowerIn(from, to))
+		}
 
 		// 添加替换字符串
-		result.WriteString(new)
-
-		// 更新位置
 		lastEnd = actualIndex + len(old)
+		repl := new
+		if keepCase {
+			repl = matchCase(new, old, f.s[to:endOffsetIn(f.offsets, lastEnd)])
+		}
+		b.write(repl, strings.ToLower(repl))
+		n++
 	}
 
 	// 添加剩余部分
-	result.WriteString(s[lastEnd:])
+	from := endOffsetIn(f.offsets, lastEnd)
+	b.write(f.s[from:], f.lowerIn(from, len(f.s)))
+	return b.folded(), n
+}
+
+// lowerIn 返回 s[from:to] 的折叠形式；只有 s 全为 ASCII 时才能直接切片，否则返回空串，由 foldedBuilder 逐字符折叠
+func (f foldedString) lowerIn(from, to int) string {
+	if f.offsets != nil {
+		return ""
+	}
+	return f.lower[from:to]
+}
+
+// foldedBuilder 同时拼接字符串和它的折叠形式
+type foldedBuilder struct {
+	s, lower strings.Builder
+	offsets  []int
+	track    bool // 是否逐字符折叠并记录偏移；原串和替换都是 ASCII 时偏移一一对应，无需记录
+}
+
+// write 追加 seg；track 为 false 时 seg 全为 ASCII，lower 是它的折叠形式，否则忽略 lower 逐字符折叠
+func (b *foldedBuilder) write(seg, lower string) {
+	if !b.track {
+		b.s.WriteString(seg)
+		b.lower.WriteString(lower)
+		return
+	}
+	base := b.s.Len()
+	b.s.WriteString(seg)
+	for i, r := range seg {
+		writeFolded(&b.lower, r)
+		for len(b.offsets) < b.lower.Len() {
+			b.offsets = append(b.offsets, base+i)
+		}
+	}
+}
+
+// folded 返回拼接结果
+func (b *foldedBuilder) folded() foldedString {
+	s := b.s.String()
+	if !b.track {
+		return foldedString{s: s, lower: b.lower.String()}
+	}
+	return foldedString{s: s, lower: b.lower.String(), offsets: append(b.offsets, len(s))}
+}
+
+// matchCase 让替换形式 repl 沿用被替换的原文 span 的大小写：repl 中的每个字母对应到小写模式 pattern 中
+// 依次出现的同一字母，原文该位置是大写时输出大写。如 pattern 为 "union select"、span 为 "UnIoN sElEcT" 时
+// "union_select" 变为 "UnIoN_sElEcT"。span 与 pattern 长度不同（含 ß、İ 等折叠后长度变化的字符）时原样返回 repl
+func matchCase(repl, pattern, span string) string {
+	if len(span) != len(pattern) {
+		return repl
+	}
+	b := []byte(repl)
+	j := 0
+	for i, c := range b {
+		if c < 'a' || c > 'z' {
+			continue
+		}
+		k := strings.IndexByte(pattern[j:], c)
+		if k < 0 {
+			continue
+		}
+		j += k
+		if span[j] >= 'A' && span[j] <= 'Z' {
+			b[i] = c - 'a' + 'A'
+		}
+		j++
+	}
+	return string(b)
+}
+
+// foldCase 返回 s 用于大小写不敏感匹配的折叠形式
+// 在 unicode.ToLower 的基础上，把 ß、ẞ 折叠为 ss，把土耳其语的 İ、ı 折叠为 i，
+// 避免借助特殊的大小写规则让关键字绕过检测
+func foldCase(s string) string {
+	if isASCII(s) {
+		return strings.ToLower(s)
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		writeFolded(&b, r)
+	}
+	return b.String()
+}
+
+// foldWithOffsets 返回 foldCase(s)，以及折叠形式中每个字节所属字符在 s 中的起始偏移，
+// 末尾额外一项为 len(s)；s 全为 ASCII 时偏移一一对应，返回 nil
+func foldWithOffsets(s string) (string, []int) {
+	if isASCII(s) {
+		return strings.ToLower(s), nil
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	offsets := make([]int, 0, len(s)+1)
+	for i, r := range s {
+		writeFolded(&b, r)
+		for len(offsets) < b.Len() {
+			offsets = append(offsets, i)
+		}
+	}
+	offsets = append(offsets, len(s))
+	return b.String(), offsets
+}
+
+// writeFolded 把 r 的折叠形式写入 b
+func writeFolded(b *strings.Builder, r rune) {
+	switch r {
+	case 'ß', 'ẞ':
+		b.WriteString("ss")
+	case 'İ', 'ı':
+		b.WriteByte('i')
+	default:
+		b.WriteRune(unicode.ToLower(r))
+	}
+}
+
+// foldsToASCII 判断非 ASCII 字符 r 的折叠形式是否包含 ASCII 字符
+func foldsToASCII(r rune) bool {
+	switch r {
+	case 'ß', 'ẞ', 'İ', 'ı':
+		return true
+	}
+	return unicode.ToLower(r) < utf8.RuneSelf
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// offsetIn 把折叠形式中的起始位置 i 映射为原串中所属字符的起始偏移，offsets 为 nil 时原样返回
+func offsetIn(offsets []int, i int) int {
+	if offsets == nil {
+		return i
+	}
+	return offsets[i]
+}
 
-	return result.String()
+// endOffsetIn 把折叠形式中的结束位置 i 映射为原串中的偏移；
+// i 落在一个字符的折叠形式中间时（如 ß 折叠出的两个 s 之间），映射到该字符之后
+func endOffsetIn(offsets []int, i int) int {
+	if offsets == nil {
+		return i
+	}
+	for i > 0 && i < len(offsets)-1 && offsets[i] == offsets[i-1] {
+		i++
+	}
+	return offsets[i]
 }
 
 func quoteString(s string) string {
@@ -524,3 +2632,22 @@ func quoteString(s string) string {
 	s = strings.ReplaceAll(s, "\x1a", "\\Z") // Control-Z转义
 	return "'" + s + "'"
 }
+
+// EscapeLike 转义 LIKE 模式中的通配符 %、_ 和转义符 escapeChar 本身，使 s 按字面匹配
+// 结果应作为参数传给 Expand 等（不要再自行拼接引号），并在 SQL 中声明相同的转义符，例如
+// Expand("SELECT * FROM t WHERE name LIKE ? ESCAPE '!'", []interface{}{EscapeLike(s, '!') + "%"})
+// escapeChar 必须是 ASCII 字符；使用反斜杠时 MySQL 默认模式下字面量中还会再转义一次，无需额外处理
+func EscapeLike(s string, escapeChar byte) string {
+	if strings.IndexByte(s, '%') < 0 && strings.IndexByte(s, '_') < 0 && strings.IndexByte(s, escapeChar) < 0 {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s) + 8)
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c == '%' || c == '_' || c == escapeChar {
+			b.WriteByte(escapeChar)
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
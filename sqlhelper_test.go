@@ -1,8 +1,25 @@
 package sqlhelper
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"math/big"
+	"net/url"
+	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
+	"unicode/utf8"
 )
 
 // TestParamValidators 测试各个参数验证器
@@ -189,6 +206,59 @@ func TestTypeAwareProcessor(t *testing.T) {
 	}
 }
 
+// TestProcessorClone 测试副本与原处理器互不影响
+func TestProcessorClone(t *testing.T) {
+	original := NewTypeAwareProcessor()
+	original.EnableStats()
+	original.ProcessString("x", ParamTypeID)
+
+	clone := original.Clone()
+	clone.RegisterValidator(prefixValidator{paramType: ParamTypeName, prefix: "tenant:"})
+	if err := clone.SetMaxLength(ParamTypeID, 3); err != nil {
+		t.Fatalf("SetMaxLength() error = %v", err)
+	}
+
+	if got := clone.ProcessString("张三", ParamTypeName); got != "tenant:张三" {
+		t.Errorf("clone.ProcessString() = %q, want %q", got, "tenant:张三")
+	}
+	if got := original.ProcessString("张三", ParamTypeName); got != "张三" {
+		t.Errorf("original.ProcessString() = %q, want %q", got, "张三")
+	}
+	if got := clone.ProcessString("abcdef", ParamTypeID); got != "abc" {
+		t.Errorf("clone.ProcessString() = %q, want %q", got, "abc")
+	}
+	if got := original.ProcessString("abcdef", ParamTypeID); got != "abcdef" {
+		t.Errorf("original.ProcessString() = %q, want %q", got, "abcdef")
+	}
+
+	// 统计各自独立
+	if got := clone.Stats().Total.Processed; got != 2 {
+		t.Errorf("clone.Stats().Total.Processed = %d, want 2", got)
+	}
+	if got := original.Stats().Total.Processed; got != 3 {
+		t.Errorf("original.Stats().Total.Processed = %d, want 3", got)
+	}
+}
+
+// TestNewEmptyProcessor 测试空处理器只使用注册的验证器，未注册时退化为默认的通用验证器
+func TestNewEmptyProcessor(t *testing.T) {
+	processor := NewEmptyProcessor()
+	if got, want := processor.ProcessString("1 union select 2", ParamTypeName), (GenericValidator{}).Validate("1 union select 2"); got != want {
+		t.Errorf("ProcessString() = %q, want %q", got, want)
+	}
+
+	processor.RegisterValidator(IDValidator{})
+	if got := processor.ProcessString("a;b", ParamTypeID); got != "a_b" {
+		t.Errorf("ProcessString() = %q, want %q", got, "a_b")
+	}
+	if _, ok := processor.GetValidator(ParamTypeEmail).(GenericValidator); !ok {
+		t.Errorf("GetValidator(ParamTypeEmail) = %T, want GenericValidator", processor.GetValidator(ParamTypeEmail))
+	}
+	if err := processor.SetMaxLength(ParamTypeName, 10); err == nil {
+		t.Error("SetMaxLength(未注册的类型) error = nil")
+	}
+}
+
 // TestTypeAwareLiteral 测试集成类型感知的literal函数
 func TestTypeAwareLiteral(t *testing.T) {
 	tests := []struct {
@@ -429,9 +499,9 @@ func TestLiteral(t *testing.T) {
 			wantErr: false,
 		},
 		{
-			name:    "包含SQL注入的字节数组会被清理",
+			name:    "字节数组按二进制数据处理，只转义引号",
 			input:   []byte("'; UNION SELECT * FROM users--"),
-			want:    "'''; union_select * FROM users__'",
+			want:    "'''; UNION SELECT * FROM users--'",
 			wantErr: false,
 		},
 	}
@@ -450,6 +520,42 @@ func TestLiteral(t *testing.T) {
 	}
 }
 
+// TestBytesLiteralRoundTrip []byte 按二进制数据处理，任意字节都能按原样还原
+func TestBytesLiteralRoundTrip(t *testing.T) {
+	data := make([]byte, 0, 256+32)
+	for i := 0; i < 256; i++ {
+		data = append(data, byte(i))
+	}
+	data = append(data, "'; UNION SELECT * FROM users--\xef\xbc\xa1"...)
+
+	dialects := []Dialect{DialectMySQL, DialectANSI, DialectMySQLNoBackslashEscapes, DialectSQLite}
+	for _, d := range dialects {
+		lit, err := literalConfig{dialect: d}.literal(data)
+		if err != nil {
+			t.Fatalf("literal(%v) error = %v", d, err)
+		}
+		parse := parseStandardString
+		if d == DialectMySQL {
+			parse = parseMySQLString
+		}
+		decoded, err := parse(lit)
+		if err != nil {
+			t.Fatalf("literal(%v) = %q is malformed: %v", d, lit, err)
+		}
+		if decoded != string(data) {
+			t.Errorf("literal(%v) decodes to %q, want %q", d, decoded, data)
+		}
+	}
+
+	// PostgreSQL 的文本字面量不能包含空字节，无法原样表示时返回 error 而不是丢弃数据
+	if lit, err := (literalConfig{dialect: DialectPostgres}).literal(data); err == nil {
+		t.Errorf("literal(Postgres) = %q, want error", lit)
+	}
+	if lit, err := (literalConfig{dialect: DialectPostgres}).literal(data[1:]); err != nil || lit[1:len(lit)-1] != strings.ReplaceAll(string(data[1:]), "'", "''") {
+		t.Errorf("literal(Postgres) without NUL = %q, %v", lit, err)
+	}
+}
+
 func TestExpand(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -507,4 +613,2917 @@ func TestExpand(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+// TestValidatorMaxLength 测试验证器的长度限制配置
+func TestValidatorMaxLength(t *testing.T) {
+	long := strings.Repeat("a", 20000)
+	tests := []struct {
+		name      string
+		validator ParamValidator
+		wantLen   int
+	}{
+		{"IDValidator - 默认值", IDValidator{}, DefaultIDMaxLength},
+		{"NameValidator - 默认值", NameValidator{}, DefaultNameMaxLength},
+		{"DescriptionValidator - 默认值", DescriptionValidator{}, DefaultDescriptionMaxLength},
+		{"GenericValidator - 默认值", GenericValidator{}, DefaultGenericMaxLength},
+		{"IDValidator - 自定义", NewIDValidator(36), 36},
+		{"NameValidator - 自定义", NewNameValidator(255), 255},
+		{"DescriptionValidator - 自定义", NewDescriptionValidator(15000), 15000},
+		{"GenericValidator - 自定义", NewGenericValidator(64), 64},
+		{"IDValidator - 不限制", NewIDValidator(0), len(long)},
+		{"DescriptionValidator - 不限制", DescriptionValidator{MaxLength: NoLengthLimit}, len(long)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(tt.validator.Validate(long)); got != tt.wantLen {
+				t.Errorf("%s: len(Validate()) = %d, want %d", tt.name, got, tt.wantLen)
+			}
+		})
+	}
+}
+
+// TestProcessorSetMaxLength 测试处理器覆盖长度限制
+func TestProcessorSetMaxLength(t *testing.T) {
+	processor := NewTypeAwareProcessor()
+	if err := processor.SetMaxLength(ParamTypeName, 10); err != nil {
+		t.Fatalf("SetMaxLength() error = %v", err)
+	}
+	if got := processor.ProcessString(strings.Repeat("b", 100), ParamTypeName); got != strings.Repeat("b", 10) {
+		t.Errorf("ProcessString() = %q, want 10 chars", got)
+	}
+	// 其他类型不受影响
+	if got := len(processor.ProcessString(strings.Repeat("b", 1000), ParamTypeGeneric)); got != 1000 {
+		t.Errorf("len(ProcessString(Generic)) = %d, want 1000", got)
+	}
+	if err := processor.SetMaxLength(ParamType(99), 10); err == nil {
+		t.Error("SetMaxLength() on unregistered type should return error")
+	}
+}
+
+// TestExpandSliceIn 测试切片参数展开为 IN 子句
+func TestExpandSliceIn(t *testing.T) {
+	type myBytes []byte
+	tests := []struct {
+		name    string
+		sql     string
+		vars    []interface{}
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "整数切片",
+			sql:  "SELECT * FROM users WHERE id IN (?)",
+			vars: []interface{}{[]int{1, 2, 3}},
+			want: "SELECT * FROM users WHERE id IN (1,2,3)",
+		},
+		{
+			name: "int64切片",
+			sql:  "SELECT * FROM users WHERE id IN (?)",
+			vars: []interface{}{[]int64{10, 20}},
+			want: "SELECT * FROM users WHERE id IN (10,20)",
+		},
+		{
+			name: "字符串切片逐个清理",
+			sql:  "SELECT * FROM users WHERE name IN (?) AND age > ?",
+			vars: []interface{}{[]string{"tom", "'; DROP TABLE users;--"}, 18},
+			want: "SELECT * FROM users WHERE name IN ('tom','''; drop_table users;__') AND age > 18",
+		},
+		{
+			name: "interface切片",
+			sql:  "SELECT * FROM t WHERE v IN (?)",
+			vars: []interface{}{[]interface{}{1, "a", nil}},
+			want: "SELECT * FROM t WHERE v IN (1,'a',NULL)",
+		},
+		{
+			name: "空切片",
+			sql:  "SELECT * FROM users WHERE id IN (?)",
+			vars: []interface{}{[]int{}},
+			want: "SELECT * FROM users WHERE id IN (NULL)",
+		},
+		{
+			name: "命名字节切片按字符串处理",
+			sql:  "SELECT * FROM t WHERE v = ?",
+			vars: []interface{}{myBytes("hello")},
+			want: "SELECT * FROM t WHERE v = 'hello'",
+		},
+		{
+			name:    "不支持的元素类型",
+			sql:     "SELECT * FROM t WHERE v IN (?)",
+			vars:    []interface{}{[]struct{}{{}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Expand(tt.sql, tt.vars)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expand(%q, %v) error = %v, wantErr %v", tt.sql, tt.vars, err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Expand(%q, %v) = %q, want %q", tt.sql, tt.vars, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestProcessStringStrict 测试严格模式在检测到危险模式时返回错误
+func TestProcessStringStrict(t *testing.T) {
+	processor := NewTypeAwareProcessor()
+
+	tests := []struct {
+		name        string
+		input       string
+		paramType   ParamType
+		expected    string
+		wantPattern string
+		wantOffset  int
+	}{
+		{
+			name:      "ID类型 - 正常",
+			input:     "project_123",
+			paramType: ParamTypeID,
+			expected:  "project_123",
+		},
+		{
+			name:        "ID类型 - 非法字符",
+			input:       "project@123",
+			paramType:   ParamTypeID,
+			wantPattern: "@",
+			wantOffset:  7,
+		},
+		{
+			name:      "名称类型 - 正常",
+			input:     "北京朝阳区项目",
+			paramType: ParamTypeName,
+			expected:  "北京朝阳区项目",
+		},
+		{
+			name:        "名称类型 - DROP TABLE",
+			input:       "abc'; DROP TABLE users",
+			paramType:   ParamTypeName,
+			wantPattern: "'; drop table",
+			wantOffset:  3,
+		},
+		{
+			name:        "描述类型 - UNION SELECT",
+			input:       "描述 UNION SELECT",
+			paramType:   ParamTypeDescription,
+			wantPattern: "union select",
+			wantOffset:  7,
+		},
+		{
+			name:        "通用类型 - 注释",
+			input:       "test--",
+			paramType:   ParamTypeGeneric,
+			wantPattern: "--",
+			wantOffset:  4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := processor.ProcessStringStrict(tt.input, tt.paramType)
+			if tt.wantPattern == "" {
+				if err != nil {
+					t.Fatalf("ProcessStringStrict(%q) error = %v", tt.input, err)
+				}
+				if result != tt.expected {
+					t.Errorf("ProcessStringStrict(%q) = %q, want %q", tt.input, result, tt.expected)
+				}
+				return
+			}
+			var injErr *InjectionError
+			if !errors.As(err, &injErr) {
+				t.Fatalf("ProcessStringStrict(%q) error = %v, want *InjectionError", tt.input, err)
+			}
+			if injErr.Pattern != tt.wantPattern || injErr.Offset != tt.wantOffset || injErr.Type != tt.paramType {
+				t.Errorf("ProcessStringStrict(%q) error = %+v, want pattern %q at %d", tt.input, injErr, tt.wantPattern, tt.wantOffset)
+			}
+		})
+	}
+}
+
+// TestTimeLiteral 测试时间参数的渲染选项
+func TestTimeLiteral(t *testing.T) {
+	defer func(layout string, utc, zeroNull bool) {
+		TimeLayout, TimeInUTC, ZeroTimeAsNull = layout, utc, zeroNull
+	}(TimeLayout, TimeInUTC, ZeroTimeAsNull)
+
+	shanghai := time.FixedZone("CST", 8*3600)
+	ts := time.Date(2024, 3, 1, 8, 30, 15, 123456000, shanghai)
+
+	tests := []struct {
+		name     string
+		layout   string
+		utc      bool
+		zeroNull bool
+		input    time.Time
+		expected string
+	}{
+		{"默认格式", DefaultTimeLayout, false, false, ts, "'2024-03-01 08:30:15'"},
+		{"微秒精度", TimeLayoutMicro, false, false, ts, "'2024-03-01 08:30:15.123456'"},
+		{"转换为UTC", DefaultTimeLayout, true, false, ts, "'2024-03-01 00:30:15'"},
+		{"微秒精度并转换为UTC", TimeLayoutMicro, true, false, ts, "'2024-03-01 00:30:15.123456'"},
+		{"零值默认", DefaultTimeLayout, false, false, time.Time{}, "'0001-01-01 00:00:00'"},
+		{"零值渲染为NULL", DefaultTimeLayout, false, true, time.Time{}, "NULL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			TimeLayout, TimeInUTC, ZeroTimeAsNull = tt.layout, tt.utc, tt.zeroNull
+			result, err := literal(tt.input)
+			if err != nil {
+				t.Fatalf("literal(%v) error = %v", tt.input, err)
+			}
+			if result != tt.expected {
+				t.Errorf("literal(%v) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestTimePointerLiteral 测试 *time.Time 和零值时间在 ZeroTimeAsNull 两种设置下的渲染
+func TestTimePointerLiteral(t *testing.T) {
+	defer func(layout string, utc, zeroNull bool) {
+		TimeLayout, TimeInUTC, ZeroTimeAsNull = layout, utc, zeroNull
+	}(TimeLayout, TimeInUTC, ZeroTimeAsNull)
+	TimeLayout, TimeInUTC = DefaultTimeLayout, false
+
+	ts := time.Date(2024, 3, 1, 8, 30, 15, 0, time.UTC)
+	zero := time.Time{}
+
+	tests := []struct {
+		name     string
+		zeroNull bool
+		input    interface{}
+		expected string
+	}{
+		{"nil指针", false, (*time.Time)(nil), "NULL"},
+		{"nil指针且零值为NULL", true, (*time.Time)(nil), "NULL"},
+		{"零值", false, zero, "'0001-01-01 00:00:00'"},
+		{"零值渲染为NULL", true, zero, "NULL"},
+		{"零值指针", false, &zero, "'0001-01-01 00:00:00'"},
+		{"零值指针渲染为NULL", true, &zero, "NULL"},
+		{"正常时间", false, ts, "'2024-03-01 08:30:15'"},
+		{"正常时间且零值为NULL", true, ts, "'2024-03-01 08:30:15'"},
+		{"正常时间指针", false, &ts, "'2024-03-01 08:30:15'"},
+		{"正常时间指针且零值为NULL", true, &ts, "'2024-03-01 08:30:15'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ZeroTimeAsNull = tt.zeroNull
+			result, err := Expand("SELECT * FROM t WHERE created_at = ?", []interface{}{tt.input})
+			if err != nil {
+				t.Fatalf("Expand(%v) error = %v", tt.input, err)
+			}
+			if want := "SELECT * FROM t WHERE created_at = " + tt.expected; result != want {
+				t.Errorf("Expand(%v) = %q, want %q", tt.input, result, want)
+			}
+		})
+	}
+}
+
+// TestNullTypesLiteral 测试 sql.Null* 类型的渲染
+func TestNullTypesLiteral(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 8, 30, 15, 0, time.UTC)
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected string
+	}{
+		{"NullString有效", sql.NullString{String: "hello", Valid: true}, "'hello'"},
+		{"NullString有效且被清理", sql.NullString{String: "'; DROP TABLE users;--", Valid: true}, "'''; drop_table users;__'"},
+		{"NullString无效", sql.NullString{String: "hello"}, "NULL"},
+		{"NullInt64有效", sql.NullInt64{Int64: 42, Valid: true}, "42"},
+		{"NullInt64无效", sql.NullInt64{Int64: 42}, "NULL"},
+		{"NullInt32有效", sql.NullInt32{Int32: -7, Valid: true}, "-7"},
+		{"NullInt32无效", sql.NullInt32{}, "NULL"},
+		{"NullInt16有效", sql.NullInt16{Int16: 16, Valid: true}, "16"},
+		{"NullInt16无效", sql.NullInt16{}, "NULL"},
+		{"NullByte有效", sql.NullByte{Byte: 8, Valid: true}, "8"},
+		{"NullByte无效", sql.NullByte{}, "NULL"},
+		{"NullFloat64有效", sql.NullFloat64{Float64: 1.5, Valid: true}, "1.5"},
+		{"NullFloat64无效", sql.NullFloat64{Float64: 1.5}, "NULL"},
+		{"NullBool有效", sql.NullBool{Bool: true, Valid: true}, "true"},
+		{"NullBool无效", sql.NullBool{Bool: true}, "NULL"},
+		{"NullTime有效", sql.NullTime{Time: ts, Valid: true}, "'2024-03-01 08:30:15'"},
+		{"NullTime无效", sql.NullTime{Time: ts}, "NULL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := literal(tt.input)
+			if err != nil {
+				t.Fatalf("literal(%v) error = %v", tt.input, err)
+			}
+			if result != tt.expected {
+				t.Errorf("literal(%v) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestPointerLiteral 测试指针参数的解引用
+func TestPointerLiteral(t *testing.T) {
+	str := "hello"
+	attack := "'; DROP TABLE users;--"
+	num := 42
+	i64 := int64(-9)
+	f := 1.25
+	b := true
+	ts := time.Date(2024, 3, 1, 8, 30, 15, 0, time.UTC)
+	ns := sql.NullString{String: "x", Valid: true}
+	pstr := &str
+
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected string
+	}{
+		{"*string", &str, "'hello'"},
+		{"*string被清理", &attack, "'''; drop_table users;__'"},
+		{"*int", &num, "42"},
+		{"*int64", &i64, "-9"},
+		{"*float64", &f, "1.25"},
+		{"*bool", &b, "true"},
+		{"*time.Time", &ts, "'2024-03-01 08:30:15'"},
+		{"**string", &pstr, "'hello'"},
+		{"*sql.NullString", &ns, "'x'"},
+		{"nil *string", (*string)(nil), "NULL"},
+		{"nil *int", (*int)(nil), "NULL"},
+		{"nil *time.Time", (*time.Time)(nil), "NULL"},
+		{"nil *sql.NullString", (*sql.NullString)(nil), "NULL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := literal(tt.input)
+			if err != nil {
+				t.Fatalf("literal(%v) error = %v", tt.input, err)
+			}
+			if result != tt.expected {
+				t.Errorf("literal(%v) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestValidatorsDeterministic 测试同一输入多次验证得到完全相同的输出
+func TestValidatorsDeterministic(t *testing.T) {
+	// 多个相互重叠的模式同时出现
+	input := "x' or '1'='1; delete from t-- /*/ union all select #1 concat waitfor delay"
+	validators := []ParamValidator{IDValidator{}, NameValidator{}, DescriptionValidator{}, GenericValidator{}}
+
+	for _, validator := range validators {
+		first := validator.Validate(input)
+		for i := 0; i < 200; i++ {
+			if got := validator.Validate(input); got != first {
+				t.Fatalf("%T.Validate(%q) run %d = %q, first run = %q", validator, input, i, got, first)
+			}
+		}
+	}
+	first := sanitizeStringInput(input)
+	for i := 0; i < 200; i++ {
+		if got := sanitizeStringInput(input); got != first {
+			t.Fatalf("sanitizeStringInput(%q) run %d = %q, first run = %q", input, i, got, first)
+		}
+	}
+}
+
+// TestProcessorConcurrentAccess 测试并发注册验证器与处理字符串，需配合 -race 运行
+func TestProcessorConcurrentAccess(t *testing.T) {
+	processor := NewTypeAwareProcessor()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(n int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				processor.RegisterValidator(NewNameValidator(100 + n))
+				_ = processor.SetMaxLength(ParamTypeGeneric, 100+j)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if got := processor.ProcessString("project@123", ParamTypeID); got != "project_123" {
+					t.Errorf("ProcessString() = %q, want %q", got, "project_123")
+				}
+				_ = processor.ProcessString("北京项目", ParamTypeName)
+				_, _ = processor.ProcessStringStrict("test", ParamTypeGeneric)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestExpandError 测试占位符不匹配时返回结构化错误
+func TestExpandError(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		vars []interface{}
+		want ExpandError
+	}{
+		{
+			name: "参数过少",
+			sql:  "SELECT * FROM users WHERE id = ? AND name = ?",
+			vars: []interface{}{123},
+			want: ExpandError{Placeholders: 2, Args: 1, Offset: 44, Msg: "占位符个数 > 参数个数"},
+		},
+		{
+			name: "参数过多",
+			sql:  "SELECT * FROM users WHERE id = ?",
+			vars: []interface{}{123, "extra"},
+			want: ExpandError{Placeholders: 1, Args: 2, Offset: -1, Msg: "占位符个数 < 参数个数"},
+		},
+		{
+			name: "没有参数",
+			sql:  "SELECT ?",
+			vars: nil,
+			want: ExpandError{Placeholders: 1, Args: 0, Offset: 7, Msg: "占位符个数 > 参数个数"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Expand(tt.sql, tt.vars)
+			var expandErr *ExpandError
+			if !errors.As(err, &expandErr) {
+				t.Fatalf("Expand(%q) error = %v, want *ExpandError", tt.sql, err)
+			}
+			if *expandErr != tt.want {
+				t.Errorf("Expand(%q) error = %+v, want %+v", tt.sql, *expandErr, tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want.Msg) {
+				t.Errorf("Error() = %q, want it to contain %q", err.Error(), tt.want.Msg)
+			}
+		})
+	}
+}
+
+// TestCustomTypeInferrer 测试自定义名称识别规则的类型推断器
+func TestCustomTypeInferrer(t *testing.T) {
+	medical := NewTypeInferrer(InferOptions{
+		NameKeywords: []string{"Clinic", "Hospital", "Dr."},
+	})
+	cyrillic := NewTypeInferrer(InferOptions{
+		NameRanges: []UnicodeRange{{0x0400, 0x04ff}},
+	})
+
+	tests := []struct {
+		name     string
+		inferrer *TypeInferrer
+		input    string
+		expected ParamType
+	}{
+		{"医疗关键字", medical, "St. Mary Hospital", ParamTypeName},
+		{"医疗关键字 - 医生", medical, "Dr. Smith", ParamTypeName},
+		{"自定义规则不再识别中文", medical, "北京朝阳区项目", ParamTypeGeneric},
+		{"ID检测不受影响", medical, "patient_001", ParamTypeID},
+		{"描述检测不受影响", medical, "line1\nline2", ParamTypeDescription},
+		{"自定义字符范围", cyrillic, "Иван Петров", ParamTypeName},
+		{"默认推断器", &TypeInferrer{}, "北京朝阳区项目", ParamTypeName},
+		{"默认推断器 - 医疗文本", &TypeInferrer{}, "St. Mary Hospital", ParamTypeGeneric},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.inferrer.InferType(tt.input); result != tt.expected {
+				t.Errorf("InferType(%q) = %v, want %v", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+// upperValidator 测试用验证器，把输入转为大写
+type upperValidator struct{}
+
+func (upperValidator) GetType() ParamType           { return ParamTypeGeneric }
+func (upperValidator) Validate(value string) string { return strings.ToUpper(value) }
+
+// TestSetGlobalPipeline 测试替换全局处理器和推断器
+func TestSetGlobalPipeline(t *testing.T) {
+	defer SetGlobalProcessor(nil)
+	defer SetGlobalInferrer(nil)
+
+	processor := NewTypeAwareProcessor()
+	processor.RegisterValidator(upperValidator{})
+	SetGlobalProcessor(processor)
+
+	got, err := Expand("SELECT ?", []interface{}{"general text"})
+	if err != nil || got != "SELECT 'GENERAL TEXT'" {
+		t.Errorf("Expand() with custom processor = %q, %v", got, err)
+	}
+
+	// 自定义推断器把医疗名称识别为名称类型后交给名称验证器
+	SetGlobalInferrer(NewTypeInferrer(InferOptions{NameKeywords: []string{"Clinic"}}))
+	SetGlobalProcessor(nil)
+	got, err = Literal("Concat Clinic")
+	if err != nil || got != "'_concat_ Clinic'" {
+		t.Errorf("Literal() with custom inferrer = %q, %v", got, err)
+	}
+
+	SetGlobalInferrer(nil)
+	got, err = Literal("Concat Clinic")
+	if err != nil || got != "'Concat Clinic'" {
+		t.Errorf("Literal() after reset = %q, %v", got, err)
+	}
+}
+
+// TestBigNumberLiteral 测试 big.Int、big.Float 的渲染，不能丢失精度
+func TestBigNumberLiteral(t *testing.T) {
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	negative, _ := new(big.Int).SetString("-98765432109876543210", 10)
+	precise, _ := new(big.Float).SetPrec(200).SetString("12345678901234567890.123456789012345678901")
+	large := new(big.Float).SetPrec(100).SetInt(huge)
+
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected string
+		wantErr  bool
+	}{
+		{"超过int64的整数", huge, "123456789012345678901234567890", false},
+		{"负的大整数", negative, "-98765432109876543210", false},
+		{"小整数", big.NewInt(42), "42", false},
+		{"nil *big.Int", (*big.Int)(nil), "NULL", false},
+		{"高精度小数", precise, "12345678901234567890.123456789012345678901", false},
+		{"大浮点数不使用科学计数法", large, "123456789012345678901234567890", false},
+		{"普通小数", big.NewFloat(0.5), "0.5", false},
+		{"nil *big.Float", (*big.Float)(nil), "NULL", false},
+		{"无穷大", new(big.Float).SetInf(false), "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := literal(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("literal(%v) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if result != tt.expected {
+				t.Errorf("literal(%v) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestDurationLiteral 测试 time.Duration 按配置单位渲染为整数
+func TestDurationLiteral(t *testing.T) {
+	defer func(unit time.Duration) { DurationUnit = unit }(DurationUnit)
+
+	tests := []struct {
+		name     string
+		unit     time.Duration
+		input    time.Duration
+		expected string
+	}{
+		{"默认秒", time.Second, 90 * time.Minute, "5400"},
+		{"秒 - 向上取整", time.Second, 1500 * time.Millisecond, "2"},
+		{"秒 - 向下取整", time.Second, 1499 * time.Millisecond, "1"},
+		{"秒 - 负数", time.Second, -2500 * time.Millisecond, "-3"},
+		{"毫秒", time.Millisecond, 1500 * time.Millisecond, "1500"},
+		{"毫秒 - 取整", time.Millisecond, 2500 * time.Microsecond, "3"},
+		{"纳秒", time.Nanosecond, 1500 * time.Millisecond, "1500000000"},
+		{"零值", time.Second, 0, "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			DurationUnit = tt.unit
+			result, err := literal(tt.input)
+			if err != nil {
+				t.Fatalf("literal(%v) error = %v", tt.input, err)
+			}
+			if result != tt.expected {
+				t.Errorf("literal(%v) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestOnPatternMatch 测试危险模式回调按替换次数触发，未命中时不额外分配内存
+func TestOnPatternMatch(t *testing.T) {
+	counts := make(map[string]int)
+	processor := NewTypeAwareProcessor()
+	processor.OnPatternMatch = func(paramType ParamType, pattern string, original string) {
+		counts[pattern]++
+	}
+
+	tests := []struct {
+		name      string
+		input     string
+		paramType ParamType
+		want      map[string]int
+	}{
+		{"正常输入不回调", "hello world", ParamTypeGeneric, map[string]int{}},
+		{"单个模式", "1 UNION SELECT 2", ParamTypeGeneric, map[string]int{"union select": 1}},
+		{"同一模式出现多次", "a -- b -- c", ParamTypeGeneric, map[string]int{"--": 2}},
+		{"多个模式", "x'; DROP TABLE t; --", ParamTypeDescription, map[string]int{"'; drop table": 1, "--": 1}},
+		{"名称类型", "a or b and c", ParamTypeName, map[string]int{" or ": 1, " and ": 1}},
+		{"ID类型不回调", "a;b", ParamTypeID, map[string]int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k := range counts {
+				delete(counts, k)
+			}
+			processor.ProcessString(tt.input, tt.paramType)
+			if len(counts) != len(tt.want) {
+				t.Fatalf("ProcessString(%q) 回调 = %v, want %v", tt.input, counts, tt.want)
+			}
+			for pattern, n := range tt.want {
+				if counts[pattern] != n {
+					t.Errorf("ProcessString(%q) 回调 = %v, want %v", tt.input, counts, tt.want)
+				}
+			}
+		})
+	}
+
+	t.Run("未命中时不额外分配", func(t *testing.T) {
+		plain := NewTypeAwareProcessor()
+		base := testing.AllocsPerRun(100, func() { plain.ProcessString("hello world", ParamTypeName) })
+		hooked := testing.AllocsPerRun(100, func() { processor.ProcessString("hello world", ParamTypeName) })
+		if hooked > base {
+			t.Errorf("设置回调后分配次数 = %v, 未设置时 = %v", hooked, base)
+		}
+	})
+}
+
+func TestSetSanitizationLogger(t *testing.T) {
+	type entry struct {
+		paramType     ParamType
+		before, after string
+	}
+	var logged []entry
+	SetSanitizationLogger(func(paramType ParamType, before, after string) {
+		logged = append(logged, entry{paramType, before, after})
+	})
+	defer SetSanitizationLogger(nil)
+
+	NewTypeAwareProcessor().ProcessString("hello world", ParamTypeGeneric)
+	if len(logged) != 0 {
+		t.Fatalf("未改变的值不应回调，实际 %v", logged)
+	}
+
+	got, err := Expand("SELECT * FROM users WHERE name = ? AND id = ?", []interface{}{"1 UNION SELECT 2", "abc"})
+	if err != nil {
+		t.Fatalf("Expand 出错: %v", err)
+	}
+	if len(logged) != 1 || logged[0].before != "1 UNION SELECT 2" || logged[0].after == logged[0].before {
+		t.Fatalf("Expand(...) = %q, 回调 = %v，应只记录被改写的参数", got, logged)
+	}
+
+	logged = nil
+	NewTypeAwareProcessor().ProcessBytes([]byte("a;b"), ParamTypeID)
+	if len(logged) != 1 || logged[0] != (entry{ParamTypeID, "a;b", "a_b"}) {
+		t.Errorf("ProcessBytes 回调 = %v, want [{%d a;b a_b}]", logged, ParamTypeID)
+	}
+
+	logged = nil
+	SetSanitizationLogger(nil)
+	NewTypeAwareProcessor().ProcessString("a;b", ParamTypeID)
+	if len(logged) != 0 {
+		t.Errorf("取消回调后仍被调用: %v", logged)
+	}
+}
+
+// TestExpandWithCount 测试返回的替换个数，出错时为出错前已替换的个数
+func TestExpandWithCount(t *testing.T) {
+	tests := []struct {
+		name      string
+		sql       string
+		vars      []interface{}
+		want      string
+		wantCount int
+		wantErr   bool
+	}{
+		{"无占位符", "SELECT 1", nil, "SELECT 1", 0, false},
+		{"正常展开", "SELECT * FROM t WHERE a = ? AND b = ?", []interface{}{1, "x"}, "SELECT * FROM t WHERE a = 1 AND b = 'x'", 2, false},
+		{"引号中的问号不计数", "SELECT '?' FROM t WHERE a = ?", []interface{}{1}, "SELECT '?' FROM t WHERE a = 1", 1, false},
+		{"参数不足", "SELECT ?, ?, ?", []interface{}{1, 2}, "", 2, true},
+		{"参数过多", "SELECT ?", []interface{}{1, 2}, "", 1, true},
+		{"不支持的类型", "SELECT ?, ?", []interface{}{1, struct{}{}}, "", 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, n, err := ExpandWithCount(tt.sql, tt.vars)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExpandWithCount(%q) error = %v, wantErr %v", tt.sql, err, tt.wantErr)
+			}
+			if got != tt.want || n != tt.wantCount {
+				t.Errorf("ExpandWithCount(%q) = %q, %d, want %q, %d", tt.sql, got, n, tt.want, tt.wantCount)
+			}
+		})
+	}
+}
+
+// TestExpandBatch 测试多行 INSERT 的生成
+func TestExpandBatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  string
+		tmpl    string
+		rows    [][]interface{}
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "多行",
+			prefix: "INSERT INTO users (id, name)",
+			tmpl:   "?, ?",
+			rows:   [][]interface{}{{1, "john"}, {2, "jane"}},
+			want:   "INSERT INTO users (id, name) VALUES (1, 'john'),(2, 'jane')",
+		},
+		{
+			name:   "单行并去掉前缀末尾空白",
+			prefix: "INSERT INTO t (a) ",
+			tmpl:   "?",
+			rows:   [][]interface{}{{nil}},
+			want:   "INSERT INTO t (a) VALUES (NULL)",
+		},
+		{
+			name:   "模板中的常量和函数",
+			prefix: "INSERT INTO t (a, b, created_at)",
+			tmpl:   "?, 'x?', NOW()",
+			rows:   [][]interface{}{{1}, {2}},
+			want:   "INSERT INTO t (a, b, created_at) VALUES (1, 'x?', NOW()),(2, 'x?', NOW())",
+		},
+		{
+			name:   "参数会被清理",
+			prefix: "INSERT INTO t (name)",
+			tmpl:   "?",
+			rows:   [][]interface{}{{"'; DROP TABLE users;--"}},
+			want:   "INSERT INTO t (name) VALUES ('''; drop_table users;__')",
+		},
+		{
+			name:    "某一行参数个数不符",
+			prefix:  "INSERT INTO t (a, b)",
+			tmpl:    "?, ?",
+			rows:    [][]interface{}{{1, 2}, {3}},
+			wantErr: true,
+		},
+		{
+			name:    "没有行",
+			prefix:  "INSERT INTO t (a)",
+			tmpl:    "?",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandBatch(tt.prefix, tt.tmpl, tt.rows)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExpandBatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ExpandBatch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	_, err := ExpandBatch("INSERT INTO t (a, b)", "?, ?", [][]interface{}{{1, 2}, {3}})
+	var expandErr *ExpandError
+	if !errors.As(err, &expandErr) || !strings.Contains(err.Error(), "第 2 行") {
+		t.Errorf("ExpandBatch() error = %v, want *ExpandError for row 2", err)
+	}
+}
+
+// TestNameValidatorPreserveWhitespace 测试保留原始空白的名称验证
+func TestNameValidatorPreserveWhitespace(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"保留连续空格", "John  Smith", "John  Smith"},
+		{"保留首尾空白和制表符", "  \tindented name ", "  \tindented name "},
+		{"全角字符仍然规范化", "ｐｒｏｊｅｃｔ  Ａ", "project  A"},
+		{"危险模式仍然替换", "a  or  b--", "a _or_ b__"},
+	}
+
+	validator := NameValidator{PreserveWhitespace: true}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := validator.Validate(tt.input); result != tt.expected {
+				t.Errorf("Validate(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+
+	// 默认行为不变
+	if got := (NameValidator{}).Validate("  John  Smith "); got != "John Smith" {
+		t.Errorf("NameValidator{}.Validate() = %q, want %q", got, "John Smith")
+	}
+	// 调整长度限制时保留该选项
+	limited := validator.WithMaxLength(6)
+	if got := limited.Validate("a  b  c  d"); got != "a  b  " {
+		t.Errorf("WithMaxLength(6).Validate() = %q, want %q", got, "a  b  ")
+	}
+}
+
+// TestTimeBasedPatterns 测试各验证器对时间盲注的整体识别
+func TestTimeBasedPatterns(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  map[ParamType]string
+	}{
+		{"waitfor delay", "1'; WAITFOR DELAY '0:0:5'", map[ParamType]string{
+			ParamTypeGeneric:     "1'; waitfor_delay '0:0:5'",
+			ParamTypeDescription: "1'; waitfor_delay '0:0:5'",
+			ParamTypeName:        "1'; _waitfor_ _delay_ '0:0:5'",
+		}},
+		{"benchmark(", "1 AND BENCHMARK(5000000,MD5(1))", map[ParamType]string{
+			ParamTypeGeneric:     "1 AND benchmark_(5000000,MD5(1))",
+			ParamTypeDescription: "1 AND benchmark_(5000000,MD5(1))",
+			ParamTypeName:        "1_and_benchmark_(5000000,MD5(1))",
+		}},
+		{"sleep(", "x' AND SLEEP(5)", map[ParamType]string{
+			ParamTypeGeneric:     "x' AND sleep_(5)",
+			ParamTypeDescription: "x' AND sleep_(5)",
+			ParamTypeName:        "x'_and_sleep_(5)",
+		}},
+		{"pg_sleep(", "1; SELECT pg_sleep(5)", map[ParamType]string{
+			ParamTypeGeneric:     "1; SELECT pg_sleep_(5)",
+			ParamTypeDescription: "1; SELECT pg_sleep_(5)",
+			ParamTypeName:        "1; SELECT pg_sleep_(5)",
+		}},
+		{"普通文字不受影响", "sleep well, no delay", map[ParamType]string{
+			ParamTypeGeneric:     "sleep well, no delay",
+			ParamTypeDescription: "sleep well, no delay",
+		}},
+	}
+
+	processor := NewTypeAwareProcessor()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for paramType, want := range tt.want {
+				if got := processor.ProcessString(tt.input, paramType); got != want {
+					t.Errorf("ProcessString(%q, %d) = %q, want %q", tt.input, paramType, got, want)
+				}
+				if _, err := processor.ProcessStringStrict(tt.input, paramType); (err != nil) != (want != tt.input) {
+					t.Errorf("ProcessStringStrict(%q, %d) error = %v", tt.input, paramType, err)
+				}
+			}
+		})
+	}
+}
+
+// TestGenericValidatorPreserveWhitespace 测试保留原始空白的通用验证
+func TestGenericValidatorPreserveWhitespace(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"保留换行和缩进", "func main() {\n\tfmt.Println(1)\n}", "func main() {\n\tfmt.Println(1)\n}"},
+		{"保留多行地址", "北京市海淀区\r\n中关村大街 1 号  ", "北京市海淀区\r\n中关村大街 1 号  "},
+		{"全角字符仍然规范化", "ｃｏｄｅ\n  Ａ", "code\n  A"},
+		{"危险模式仍然替换", "x\n1 union select 2 -- y", "x\n1 union_select 2 __ y"},
+	}
+
+	validator := GenericValidator{PreserveWhitespace: true}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := validator.Validate(tt.input); result != tt.expected {
+				t.Errorf("Validate(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+
+	// 默认行为不变
+	if got := (GenericValidator{}).Validate("a\n\tb  c "); got != "a b c" {
+		t.Errorf("GenericValidator{}.Validate() = %q, want %q", got, "a b c")
+	}
+	// 阻止堆叠查询时只替换分号，不合并空白
+	stacked := GenericValidator{PreserveWhitespace: true, BlockStackedQueries: true}
+	if got := stacked.Validate("a;\n  b"); got != "a \n  b" {
+		t.Errorf("BlockStackedQueries.Validate() = %q, want %q", got, "a \n  b")
+	}
+	// 调整长度限制时保留该选项
+	limited := validator.WithMaxLength(4)
+	if got := limited.Validate("a\n\tbcd"); got != "a\n\tb" {
+		t.Errorf("WithMaxLength(4).Validate() = %q, want %q", got, "a\n\tb")
+	}
+}
+
+// TestInspectString 测试返回推断类型和清理结果
+func TestInspectString(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantType  ParamType
+		wantValue string
+	}{
+		{"ID", "user_123", ParamTypeID, "user_123"},
+		{"名称", "阳光花园 or 1", ParamTypeName, "阳光花园_or_1"},
+		{"描述", "line1\nline2 -- x", ParamTypeDescription, "line1\nline2 _- x"},
+		{"通用", "a b; drop table c", ParamTypeGeneric, "a b; drop_table c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotType, gotValue := InspectString(tt.input)
+			if gotType != tt.wantType || gotValue != tt.wantValue {
+				t.Errorf("InspectString(%q) = %v, %q, want %v, %q", tt.input, gotType, gotValue, tt.wantType, tt.wantValue)
+			}
+			// 与 Expand 的结果一致
+			lit, err := literal(tt.input)
+			if err != nil || lit != quoteString(gotValue) {
+				t.Errorf("literal(%q) = %q, %v, want %q", tt.input, lit, err, quoteString(gotValue))
+			}
+		})
+	}
+}
+
+// TestExpandUpdate 测试根据 map 生成 UPDATE 语句
+func TestExpandMany(t *testing.T) {
+	sql := "SELECT * FROM t WHERE a = ? AND b = '?' -- ?\nAND c = ?"
+	rows := [][]interface{}{
+		{1, "x"},
+		{"'; DROP TABLE users; --", nil},
+		{[]int{1, 2}, time.Date(2024, 3, 1, 8, 30, 15, 0, time.UTC)},
+	}
+
+	results, err := ExpandMany(sql, rows)
+	if err != nil {
+		t.Fatalf("ExpandMany() error = %v", err)
+	}
+	if len(results) != len(rows) {
+		t.Fatalf("ExpandMany() 返回 %d 条, want %d", len(results), len(rows))
+	}
+	// 结果与逐行调用 Expand 相同
+	for i, row := range rows {
+		want, err := Expand(sql, row)
+		if err != nil {
+			t.Fatalf("Expand() error = %v", err)
+		}
+		if results[i] != want {
+			t.Errorf("ExpandMany() 第 %d 行 = %q, want %q", i+1, results[i], want)
+		}
+	}
+
+	// 没有占位符的模板
+	if results, err := ExpandMany("SELECT 1", [][]interface{}{nil, {}}); err != nil || len(results) != 2 || results[1] != "SELECT 1" {
+		t.Errorf("ExpandMany() = %q, %v", results, err)
+	}
+	if results, err := ExpandMany(sql, nil); err != nil || len(results) != 0 {
+		t.Errorf("ExpandMany(nil) = %q, %v, want empty", results, err)
+	}
+
+	// 参数个数不符的行返回带行号的 *ExpandError
+	_, err = ExpandMany(sql, [][]interface{}{{1, 2}, {1}})
+	var expandErr *ExpandError
+	if !errors.As(err, &expandErr) || !strings.Contains(err.Error(), "第 2 行") {
+		t.Fatalf("ExpandMany() error = %v, want *ExpandError for row 2", err)
+	}
+	if expandErr.Placeholders != 2 || expandErr.Args != 1 {
+		t.Errorf("ExpandMany() error = %+v", expandErr)
+	}
+
+	// 无法渲染的参数
+	if _, err := ExpandMany(sql, [][]interface{}{{1, func() {}}}); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("ExpandMany() error = %v, want ErrUnsupportedType", err)
+	}
+}
+
+func TestExpandInChunked(t *testing.T) {
+	tests := []struct {
+		name      string
+		column    string
+		values    []interface{}
+		chunkSize int
+		expected  []string
+	}{
+		{"整除", "id", []interface{}{1, 2, 3, 4}, 2, []string{"id IN (1,2)", "id IN (3,4)"}},
+		{"不整除", "id", []interface{}{1, 2, 3, 4, 5}, 2, []string{"id IN (1,2)", "id IN (3,4)", "id IN (5)"}},
+		{"单段", "u.id", []interface{}{1, 2}, 10, []string{"u.id IN (1,2)"}},
+		{"元素正常转义", "name", []interface{}{"O'Brien", "'; DROP TABLE users; --"}, 1,
+			[]string{"name IN ('O''Brien')", "name IN ('''; drop_table users; __')"}},
+		{"空列表", "id", nil, 100, []string{"id IN (NULL)"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ExpandInChunked(tt.column, tt.values, tt.chunkSize)
+			if err != nil {
+				t.Fatalf("ExpandInChunked() error = %v", err)
+			}
+			if strings.Join(result, " OR ") != strings.Join(tt.expected, " OR ") {
+				t.Errorf("ExpandInChunked() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+
+	// 大量元素按段数拆分，每段不超过 chunkSize
+	ids := make([]interface{}, 100000)
+	for i := range ids {
+		ids[i] = i
+	}
+	chunks, err := ExpandInChunked("id", ids, 1000)
+	if err != nil {
+		t.Fatalf("ExpandInChunked() error = %v", err)
+	}
+	if len(chunks) != 100 {
+		t.Fatalf("ExpandInChunked() 返回 %d 段, want 100", len(chunks))
+	}
+	if want := "id IN (99000,"; !strings.HasPrefix(chunks[99], want) || strings.Count(chunks[99], ",") != 999 {
+		t.Errorf("ExpandInChunked() 最后一段 = %.40q...", chunks[99])
+	}
+
+	errorCases := []struct {
+		name      string
+		column    string
+		values    []interface{}
+		chunkSize int
+	}{
+		{"chunkSize为0", "id", []interface{}{1}, 0},
+		{"非法列名", "id) OR (1=1", []interface{}{1}, 10},
+		{"列名含注释", "id--", []interface{}{1, 2}, 10},
+		{"列名含井号", "id#", []interface{}{1, 2}, 10},
+		{"列名以数字开头", "1id", []interface{}{1}, 10},
+		{"不支持的元素类型", "id", []interface{}{1, func() {}}, 10},
+	}
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ExpandInChunked(tt.column, tt.values, tt.chunkSize); err == nil {
+				t.Errorf("ExpandInChunked() 期望返回 error")
+			}
+		})
+	}
+}
+
+func TestExpandUpdate(t *testing.T) {
+	tests := []struct {
+		name      string
+		table     string
+		set       map[string]interface{}
+		where     string
+		whereArgs []interface{}
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "列按名字排序",
+			table:     "users",
+			set:       map[string]interface{}{"name": "john", "age": 30, "deleted_at": nil},
+			where:     "id = ?",
+			whereArgs: []interface{}{7},
+			want:      "UPDATE users SET age=30, deleted_at=NULL, name='john' WHERE id = 7",
+		},
+		{
+			name:  "带库名的表且没有WHERE",
+			table: "app.users",
+			set:   map[string]interface{}{"active": false},
+			want:  "UPDATE app.users SET active=false",
+		},
+		{
+			name:      "值会被清理",
+			table:     "t",
+			set:       map[string]interface{}{"note": "'; DROP TABLE users;--"},
+			where:     "id IN (?)",
+			whereArgs: []interface{}{[]int{1, 2}},
+			want:      "UPDATE t SET note='''; drop_table users;__' WHERE id IN (1,2)",
+		},
+		{
+			name:    "列名注入",
+			table:   "t",
+			set:     map[string]interface{}{"a=1, admin": true},
+			wantErr: true,
+		},
+		{
+			name:    "表名注入",
+			table:   "t; DROP TABLE users",
+			set:     map[string]interface{}{"a": 1},
+			wantErr: true,
+		},
+		{
+			name:      "表名含注释",
+			table:     "t--",
+			set:       map[string]interface{}{"a": 1},
+			where:     "id = ?",
+			whereArgs: []interface{}{5},
+			wantErr:   true,
+		},
+		{
+			name:    "列名含井号",
+			table:   "t",
+			set:     map[string]interface{}{"a#": 1},
+			wantErr: true,
+		},
+		{
+			name:    "没有列",
+			table:   "t",
+			wantErr: true,
+		},
+		{
+			name:      "WHERE参数个数不符",
+			table:     "t",
+			set:       map[string]interface{}{"a": 1},
+			where:     "id = ?",
+			whereArgs: []interface{}{1, 2},
+			wantErr:   true,
+		},
+		{
+			name:      "没有WHERE却传入参数",
+			table:     "t",
+			set:       map[string]interface{}{"a": 1},
+			whereArgs: []interface{}{1},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandUpdate(tt.table, tt.set, tt.where, tt.whereArgs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExpandUpdate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ExpandUpdate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestReplaceCaseInsensitiveLengthChange 测试小写后长度变化的输入，替换位置仍对应原串
+func TestReplaceCaseInsensitiveLengthChange(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"非法UTF-8在匹配之前", "\xff\xfe'--", "\xff\xfe'__"},
+		{"İ小写后变短", "İİİİ--x", "İİİİ__x"},
+		{"İ在匹配之中", "Aİ--", "Aİ__"},
+		{"ASCII", "a -- B --", "a __ B __"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := replaceCaseInsensitive(tt.input, "--", "__"); result != tt.expected {
+				t.Errorf("replaceCaseInsensitive(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+
+	_, err := (GenericValidator{}).ValidateStrict("İİ x--")
+	var injErr *InjectionError
+	if !errors.As(err, &injErr) || injErr.Offset != len("İİ x") {
+		t.Errorf("ValidateStrict() error = %v, want offset %d", err, len("İİ x"))
+	}
+}
+
+// FuzzExpand 验证任意字符串参数展开后都恰好是一个完整的字符串字面量，
+// 且字面量解析回来正好是清理后的值，不可能逃逸出引号
+func FuzzExpand(f *testing.F) {
+	seeds := []string{
+		"john",
+		"'; DROP TABLE users;--",
+		`\'; DROP TABLE users;--`,
+		"a\x00b\x1a\n\r\t\"",
+		"ｐｒｏｊｅｃｔ'",
+		"İİİİ--x",
+		"\xff\xfe'--",
+		`\`,
+	}
+	for _, s := range seeds {
+		f.Add(s)
+	}
+
+	const prefix, suffix = "SELECT ", " AS v"
+	dialects := []Dialect{DialectMySQL, DialectANSI, DialectPostgres, DialectMySQLNoBackslashEscapes, DialectSQLite}
+	f.Fuzz(func(t *testing.T, arg string) {
+		_, want := InspectString(arg)
+		for _, d := range dialects {
+			got, err := ExpandFor(prefix+"?"+suffix, []interface{}{arg}, d)
+			if err != nil {
+				t.Fatalf("ExpandFor(%q, %v) error = %v", arg, d, err)
+			}
+			if !strings.HasPrefix(got, prefix) || !strings.HasSuffix(got, suffix) {
+				t.Fatalf("ExpandFor(%q, %v) = %q, surrounding SQL changed", arg, d, got)
+			}
+			lit := got[len(prefix) : len(got)-len(suffix)]
+			parse := parseStandardString
+			if d == DialectMySQL {
+				parse = parseMySQLString
+			}
+			decoded, err := parse(lit)
+			if err != nil {
+				t.Fatalf("ExpandFor(%q, %v) literal %q is malformed: %v", arg, d, lit, err)
+			}
+			wantD := want
+			if d == DialectPostgres {
+				wantD = strings.ReplaceAll(want, "\x00", "") // PostgreSQL 文本中的空字节被去掉
+			}
+			if decoded != wantD {
+				t.Fatalf("ExpandFor(%q, %v) literal decodes to %q, want %q", arg, d, decoded, wantD)
+			}
+		}
+	})
+}
+
+// parseStandardString 按标准SQL的字符串字面量语法解析 lit：只有两个连续单引号表示一个单引号
+// lit 必须恰好是一个完整的单引号字面量
+func parseStandardString(lit string) (string, error) {
+	if len(lit) < 2 || lit[0] != '\'' {
+		return "", errors.New("not a quoted literal")
+	}
+	var buf strings.Builder
+	for i := 1; i < len(lit); i++ {
+		if lit[i] != '\'' {
+			buf.WriteByte(lit[i])
+			continue
+		}
+		if i+1 < len(lit) && lit[i+1] == '\'' {
+			buf.WriteByte('\'')
+			i++
+			continue
+		}
+		if i != len(lit)-1 {
+			return "", fmt.Errorf("literal closed at %d before end", i)
+		}
+		return buf.String(), nil
+	}
+	return "", errors.New("unterminated literal")
+}
+
+// failWriter 写入 n 字节后返回错误
+type failWriter struct {
+	n int
+}
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		written := w.n
+		w.n = 0
+		return written, errors.New("写入失败")
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+// TestExpandTo 测试写入 io.Writer 的结果与 Expand 一致
+func TestExpandTo(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		vars    []interface{}
+		wantErr bool
+	}{
+		{"正常展开", "SELECT * FROM t WHERE a = ? AND b IN (?) -- ?", []interface{}{"x'y", []int{1, 2}}, false},
+		{"无占位符", "SELECT 1", nil, false},
+		{"参数不足", "SELECT ?, ?", []interface{}{1}, true},
+		{"参数过多", "SELECT ?", []interface{}{1, 2}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf strings.Builder
+			err := ExpandTo(&buf, tt.sql, tt.vars)
+			want, wantErr := Expand(tt.sql, tt.vars)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExpandTo(%q) error = %v, wantErr %v", tt.sql, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				var expandErr *ExpandError
+				if !errors.As(err, &expandErr) || err.Error() != wantErr.Error() {
+					t.Errorf("ExpandTo(%q) error = %v, want %v", tt.sql, err, wantErr)
+				}
+				if buf.Len() != 0 {
+					t.Errorf("ExpandTo(%q) 个数不符时写入了 %q", tt.sql, buf.String())
+				}
+				return
+			}
+			if buf.String() != want {
+				t.Errorf("ExpandTo(%q) wrote %q, want %q", tt.sql, buf.String(), want)
+			}
+		})
+	}
+
+	if err := ExpandTo(&failWriter{n: 10}, "SELECT * FROM t WHERE id = ?", []interface{}{1}); err == nil {
+		t.Error("ExpandTo() should return the writer error")
+	}
+}
+
+// TestCustomParamType 测试自定义参数类型的分配和注册
+func TestCustomParamType(t *testing.T) {
+	const n = 100
+	var (
+		mu   sync.Mutex
+		seen = make(map[ParamType]bool)
+		wg   sync.WaitGroup
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pt := NewParamType()
+			mu.Lock()
+			defer mu.Unlock()
+			if pt < ParamTypeCustomBase || seen[pt] {
+				t.Errorf("NewParamType() = %d, want unique value >= %d", pt, ParamTypeCustomBase)
+			}
+			seen[pt] = true
+		}()
+	}
+	wg.Wait()
+	if len(seen) != n {
+		t.Errorf("NewParamType() returned %d unique values, want %d", len(seen), n)
+	}
+
+	paramTypeCurrency := NewParamType()
+	processor := NewTypeAwareProcessor()
+	currency := NewWhitelistValidator(paramTypeCurrency, []string{"CNY", "USD"})
+	processor.RegisterValidator(currency)
+	if got := processor.GetValidator(paramTypeCurrency).GetType(); got != paramTypeCurrency {
+		t.Errorf("GetValidator(%d).GetType() = %d", paramTypeCurrency, got)
+	}
+	if got := processor.ProcessString("usd", paramTypeCurrency); got != "USD" {
+		t.Errorf("ProcessString(currency) = %q, want %q", got, "USD")
+	}
+	// 未注册的自定义类型使用通用验证器
+	if got := processor.ProcessString("a -- b", NewParamType()); got != "a __ b" {
+		t.Errorf("ProcessString(unregistered) = %q, want %q", got, "a __ b")
+	}
+}
+
+// TestProcessStringAnnotated 测试只标注不改写的处理方式
+func TestProcessStringAnnotated(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		paramType ParamType
+		want      string
+		wantHits  []PatternHit
+	}{
+		{
+			name:      "正常输入没有标注",
+			input:     "hello world",
+			paramType: ParamTypeGeneric,
+			want:      "hello world",
+		},
+		{
+			name:      "保留原文并按位置排序",
+			input:     "a -- b UNION SELECT c --",
+			paramType: ParamTypeGeneric,
+			want:      "a -- b UNION SELECT c --",
+			wantHits: []PatternHit{
+				{Pattern: "--", Offset: 2},
+				{Pattern: "union select", Offset: 7},
+				{Pattern: "--", Offset: 22},
+			},
+		},
+		{
+			name:      "偏移基于规范化后的字符串",
+			input:     "  ｘ　or  y ",
+			paramType: ParamTypeName,
+			want:      "x or y",
+			wantHits:  []PatternHit{{Pattern: " or ", Offset: 1}},
+		},
+		{
+			name:      "ID非法字符仍被替换",
+			input:     "ab;c d",
+			paramType: ParamTypeID,
+			want:      "ab_c_d",
+			wantHits: []PatternHit{
+				{Pattern: ";", Offset: 2, Rewritten: true},
+				{Pattern: " ", Offset: 4, Rewritten: true},
+			},
+		},
+		{
+			name:      "非内置验证器退化为ProcessString",
+			input:     `{"a": "--"}`,
+			paramType: ParamTypeJSON,
+			want:      `{"a": "--"}`,
+		},
+	}
+
+	processor := NewTypeAwareProcessor()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, hits := processor.ProcessStringAnnotated(tt.input, tt.paramType)
+			if got != tt.want {
+				t.Errorf("ProcessStringAnnotated(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+			if len(hits) != len(tt.wantHits) {
+				t.Fatalf("ProcessStringAnnotated(%q) hits = %+v, want %+v", tt.input, hits, tt.wantHits)
+			}
+			for i := range hits {
+				if hits[i] != tt.wantHits[i] {
+					t.Errorf("ProcessStringAnnotated(%q) hits = %+v, want %+v", tt.input, hits, tt.wantHits)
+					break
+				}
+			}
+		})
+	}
+}
+
+// TestTruncationRuneAware 测试截断按字符计数，多字节字符恰好位于边界时不会被截断成非法 UTF-8
+func TestTruncationRuneAware(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator ParamValidator
+		input     string
+		expected  string
+	}{
+		{"名称 - 中文恰好在边界", NewNameValidator(3), "北京朝阳", "北京朝"},
+		{"名称 - 默认限制按字符计", NameValidator{}, strings.Repeat("项", DefaultNameMaxLength+1), strings.Repeat("项", DefaultNameMaxLength)},
+		{"描述 - 混合宽度", NewDescriptionValidator(4), "ab中文cd", "ab中文"},
+		{"描述 - 表情符号", NewDescriptionValidator(2), "a😀b", "a😀"},
+		{"通用 - 不超过限制不截断", NewGenericValidator(4), "中文中文", "中文中文"},
+		{"ID - 全角字符规范化后截断", NewIDValidator(3), "ａｂｃｄ", "abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.validator.Validate(tt.input)
+			if result != tt.expected {
+				t.Errorf("Validate(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+			if !utf8.ValidString(result) {
+				t.Errorf("Validate(%q) = %q, not valid UTF-8", tt.input, result)
+			}
+		})
+	}
+}
+
+// countingCtx 在第 n 次调用 Err 时变为已取消
+type countingCtx struct {
+	context.Context
+	calls, n int
+}
+
+func (c *countingCtx) Err() error {
+	c.calls++
+	if c.calls >= c.n {
+		return context.Canceled
+	}
+	return nil
+}
+
+// TestExpandContext 测试展开过程中响应 context 取消
+func TestExpandContext(t *testing.T) {
+	sql := strings.Repeat("?,", 999) + "?"
+	vars := make([]interface{}, 1000)
+	for i := range vars {
+		vars[i] = i
+	}
+
+	got, err := ExpandContext(context.Background(), sql, vars)
+	want, _ := Expand(sql, vars)
+	if err != nil || got != want {
+		t.Fatalf("ExpandContext(Background) = %.20q, %v, want same as Expand", got, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ExpandContext(ctx, sql, vars); !errors.Is(err, context.Canceled) {
+		t.Errorf("ExpandContext(canceled) error = %v, want context.Canceled", err)
+	}
+
+	// 展开中途取消
+	mid := &countingCtx{Context: context.Background(), n: 3}
+	_, n, err := literalConfig{ctx: mid}.expand(sql, vars)
+	if !errors.Is(err, context.Canceled) || n != 2*ctxCheckInterval {
+		t.Errorf("expand() = %d, %v, want %d, context.Canceled", n, err, 2*ctxCheckInterval)
+	}
+
+	// 单个占位符展开大切片或 TypedList 的中途取消
+	ids := make([]int, 100*ctxCheckInterval)
+	values := make([]string, len(ids))
+	for i := range values {
+		values[i] = "id-" + strconv.Itoa(i)
+	}
+	for _, v := range []interface{}{ids, TypedList{Type: ParamTypeID, Values: values}} {
+		mid := &countingCtx{Context: context.Background(), n: 3}
+		if _, _, err := (literalConfig{ctx: mid}).expand("SELECT * FROM t WHERE id IN (?)", []interface{}{v}); !errors.Is(err, context.Canceled) {
+			t.Errorf("expand(%T) error = %v, want context.Canceled", v, err)
+		}
+		if mid.calls != 3 {
+			t.Errorf("expand(%T) 检查 context %d 次，want 3（取消后立即停止）", v, mid.calls)
+		}
+	}
+}
+
+// TestBlockStackedQueries 测试通用验证器阻止堆叠查询的选项
+func TestBlockStackedQueries(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		expected   string
+		wantOffset int // 严格模式下期望的错误位置，-1 表示不报错
+	}{
+		{"普通分号", "a; b", "a b", 1},
+		{"末尾分号", "select 1;", "select 1", 8},
+		{"多个分号", "a;b;;c", "a b c", 1},
+		{"引号内的分号保持不变", "note 'a;b' and \"c;d\"", "note 'a;b' and \"c;d\"", -1},
+		{"引号外的分号被替换", "'a;b'; x", "'a;b' x", 5},
+		{"未闭合的引号不保护分号", "x'; DROP TABLE t", "x' drop_table t", 1},
+		{"转义的引号", `'it\'s;ok'; y`, `'it\'s;ok' y`, 10},
+		{"全角分号规范化后同样处理", "a；b", "a b", 1},
+		{"没有分号", "hello", "hello", -1},
+	}
+
+	validator := GenericValidator{BlockStackedQueries: true}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := validator.Validate(tt.input); result != tt.expected {
+				t.Errorf("Validate(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+			_, err := validator.ValidateStrict(tt.input)
+			var injErr *InjectionError
+			if tt.wantOffset < 0 {
+				if err != nil {
+					t.Errorf("ValidateStrict(%q) error = %v, want nil", tt.input, err)
+				}
+				return
+			}
+			if !errors.As(err, &injErr) || injErr.Offset != tt.wantOffset {
+				t.Errorf("ValidateStrict(%q) error = %v, want offset %d", tt.input, err, tt.wantOffset)
+			}
+		})
+	}
+
+	// 默认不处理分号
+	if got := (GenericValidator{}).Validate("a; b"); got != "a; b" {
+		t.Errorf("GenericValidator{}.Validate() = %q, want %q", got, "a; b")
+	}
+}
+
+// testUUID 模拟 uuid.UUID：[16]byte 且实现 fmt.Stringer
+type testUUID [16]byte
+
+func (u testUUID) String() string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+}
+
+// testStatus 带 String 方法的枚举
+type testStatus int
+
+func (s testStatus) String() string {
+	return [...]string{"pending", "active"}[s]
+}
+
+// testValuerStringer 同时实现 driver.Valuer 和 fmt.Stringer
+type testValuerStringer struct{}
+
+func (testValuerStringer) Value() (driver.Value, error) { return int64(1), nil }
+func (testValuerStringer) String() string               { return "stringer" }
+
+// testStringer 的 String 返回危险内容
+type testStringer struct{ s string }
+
+func (t testStringer) String() string { return t.s }
+
+// testPtrStringer 只有指针接收者的 String 方法，如 *url.URL、*big.Rat
+type testPtrStringer struct{ s string }
+
+func (t *testPtrStringer) String() string { return t.s }
+
+// TestStringerLiteral 测试未知类型回退到 fmt.Stringer
+func TestStringerLiteral(t *testing.T) {
+	id := testUUID{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}
+	u, _ := url.Parse("https://example.com/a?b=1")
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected string
+	}{
+		{"UUID", id, "'123e4567-e89b-12d3-a456-426614174000'"},
+		{"*UUID", &id, "'123e4567-e89b-12d3-a456-426614174000'"},
+		{"枚举", testStatus(1), "'active'"},
+		{"Valuer优先于Stringer", testValuerStringer{}, "1"},
+		{"String结果同样被清理", testStringer{"x'; DROP TABLE t;--"}, "'x''; drop_table t;__'"},
+		{"值接收者的指针", &testStringer{"abc"}, "'abc'"},
+		{"指针接收者", &testPtrStringer{"abc"}, "'abc'"},
+		{"*url.URL", u, "'https://example.com/a?b=1'"},
+		{"*big.Rat", big.NewRat(1, 3), "'1/3'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := literal(tt.input)
+			if err != nil {
+				t.Fatalf("literal(%v) error = %v", tt.input, err)
+			}
+			if result != tt.expected {
+				t.Errorf("literal(%v) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+
+	type plain struct{ A int }
+	if _, err := literal(plain{1}); err == nil {
+		t.Error("literal(struct without String) should return error")
+	}
+	// 指针接收者的 String 方法不在值的方法集中
+	if _, err := literal(testPtrStringer{"abc"}); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("literal(testPtrStringer) error = %v, want ErrUnsupportedType", err)
+	}
+}
+
+// TestDescriptionStripHTML 测试描述验证器去掉 HTML 标签的选项
+func TestDescriptionStripHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"保留标签中的文本", "<p>Hello <b>world</b></p>", "Hello world"},
+		{"去掉脚本及其内容", "a<script>alert(1)</script>b", "ab"},
+		{"脚本标签大小写和属性", "a<SCRIPT type=\"text/javascript\">x()</Script >b", "ab"},
+		{"未闭合的脚本", "a<script>alert(1)", "a"},
+		{"去掉样式", "<style>p{color:red}</style>text", "text"},
+		{"事件属性随标签去掉", `<img src=x onerror="alert(1)">图片`, "图片"},
+		{"去掉HTML注释", "a<!-- hidden -->b", "ab"},
+		{"未闭合的标签被转义", "a <img src=x onerror=alert(1)", "a &lt;img src=x onerror=alert(1)"},
+		{"比较运算符保持不变", "1 < 2 and 3 > 2", "1 < 2 and 3 > 2"},
+		{"全角尖括号规范化后同样处理", "＜b＞bold＜/b＞", "bold"},
+		{"保留换行", "<p>line1</p>\n<p>line2</p>", "line1\nline2"},
+		{"SQL危险模式照常处理", "<b>x</b>'; DROP TABLE t", "x'; drop_table t"},
+	}
+
+	validator := DescriptionValidator{StripHTML: true}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := validator.Validate(tt.input); result != tt.expected {
+				t.Errorf("Validate(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+
+	// 默认不处理 HTML
+	if got := (DescriptionValidator{}).Validate("<b>x</b>"); got != "<b>x</b>" {
+		t.Errorf("DescriptionValidator{}.Validate() = %q, want unchanged", got)
+	}
+}
+
+// TestUnicodeCaseFolding 测试借助特殊大小写规则的关键字仍能被识别
+func TestUnicodeCaseFolding(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator ParamValidator
+		input     string
+		expected  string
+	}{
+		{"土耳其大写İ", GenericValidator{}, "1 UNİON SELECT 2", "1 union_select 2"},
+		{"土耳其无点ı", GenericValidator{}, "1 unıon select 2", "1 union_select 2"},
+		{"名称中的İ", NameValidator{}, "a OR b AND İ", "a_or_b_and_İ"},
+		{"描述中的ı", DescriptionValidator{}, "x; drop tabıe y", "x; drop tabıe y"},
+		{"描述中的İ", DescriptionValidator{}, "x; DROP TABLE y; İNSERT INTO z", "x; drop_table y; insert_into z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.validator.Validate(tt.input); result != tt.expected {
+				t.Errorf("Validate(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+
+	replaceTests := []struct {
+		input, old, expected string
+	}{
+		{"KLAẞE", "ss", "KLA__E"},
+		{"straße", "SS", "stra__e"},
+		{"Maß-Sache", "ss", "Ma__-Sache"},
+		{"ßs", "ss", "__s"},
+		{"xßy", "xs", "__y"},
+		{"İİ", "ii", "__"},
+	}
+	for _, tt := range replaceTests {
+		if result := replaceCaseInsensitive(tt.input, tt.old, "__"); result != tt.expected {
+			t.Errorf("replaceCaseInsensitive(%q, %q) = %q, want %q", tt.input, tt.old, result, tt.expected)
+		}
+	}
+
+	// 严格模式同样识别，偏移指向原字符
+	_, err := (GenericValidator{}).ValidateStrict("ab UNİON SELECT")
+	var injErr *InjectionError
+	if !errors.As(err, &injErr) || injErr.Pattern != "union select" || injErr.Offset != 3 {
+		t.Errorf("ValidateStrict() error = %v, want union select at 3", err)
+	}
+}
+
+func TestValidatorPatterns(t *testing.T) {
+	validators := []ParamValidator{
+		NewGenericValidator(0),
+		NewNameValidator(0),
+		NewDescriptionValidator(0),
+	}
+
+	for _, v := range validators {
+		lister, ok := v.(PatternLister)
+		if !ok {
+			t.Fatalf("%T does not implement PatternLister", v)
+		}
+		rules := lister.Patterns()
+		if len(rules) == 0 {
+			t.Errorf("%T.Patterns() is empty", v)
+		}
+		for i, rule := range rules {
+			if rule.Pattern != strings.ToLower(rule.Pattern) {
+				t.Errorf("%T pattern %q is not lower case", v, rule.Pattern)
+			}
+			// 包含另一模式的较长模式必须先应用，否则它永远不会命中
+			for _, later := range rules[i+1:] {
+				if later.Pattern != rule.Pattern && strings.Contains(later.Pattern, rule.Pattern) {
+					t.Errorf("%T pattern %q is applied before %q which contains it", v, rule.Pattern, later.Pattern)
+				}
+			}
+		}
+
+		// 修改返回的副本不影响验证器
+		rules[0].Pattern = "mutated"
+		if got := lister.Patterns()[0].Pattern; got == "mutated" {
+			t.Errorf("%T.Patterns() exposes internal state", v)
+		}
+	}
+
+	if rules := NewIDValidator(0).Patterns(); rules != nil {
+		t.Errorf("IDValidator.Patterns() = %v, want nil", rules)
+	}
+}
+
+func TestSafeASCIIFastPath(t *testing.T) {
+	inputs := []string{
+		"", "project_123", "John Smith", "user@example.com", "a.b,c:d/e",
+		"select", "union select", "UNION  SELECT", " leading", "trailing ",
+		"a--b", "xp_cmdshell", "O'Brien", "a;b", "<b>x</b>", "a\tb", "北京",
+	}
+	// full 不经过快速路径的完整处理流程
+	full := map[string]func(string) string{
+		"Generic": func(s string) string {
+			return limitLength(applyPatterns(GenericValidator{}.normalize(s, trace{}), genericPatterns, trace{}), 0, DefaultGenericMaxLength)
+		},
+		"Name": func(s string) string {
+			return limitLength(applyPatterns(NameValidator{}.normalize(s, trace{}), namePatterns, trace{}), 0, DefaultNameMaxLength)
+		},
+		"Description": func(s string) string {
+			return limitLength(applyPatterns(DescriptionValidator{}.normalize(s, trace{}), descriptionPatterns, trace{}), 0, DefaultDescriptionMaxLength)
+		},
+	}
+	validators := map[string]ParamValidator{
+		"Generic":     GenericValidator{},
+		"Name":        NameValidator{},
+		"Description": DescriptionValidator{},
+	}
+
+	for name, v := range validators {
+		for _, input := range inputs {
+			if got, want := v.Validate(input), full[name](input); got != want {
+				t.Errorf("%s.Validate(%q) = %q, full path = %q", name, input, got, want)
+			}
+		}
+	}
+
+	if !isSafeASCII("John Smith", namePatterns) {
+		t.Error("isSafeASCII(\"John Smith\") = false, want true")
+	}
+	for _, input := range []string{"union select", "a  b", "a;b", "北京"} {
+		if isSafeASCII(input, genericPatterns) {
+			t.Errorf("isSafeASCII(%q) = true, want false", input)
+		}
+	}
+}
+
+// testOrderStatus 数据库中以字符串存储的订单状态
+type testOrderStatus int
+
+const (
+	testOrderPending testOrderStatus = iota + 1
+	testOrderShipped
+)
+
+// withEnumMappers 在测试期间临时替换已注册的枚举映射函数
+func withEnumMappers(t *testing.T) {
+	globalMu.Lock()
+	saved := enumMappers
+	enumMappers = nil
+	globalMu.Unlock()
+	t.Cleanup(func() {
+		globalMu.Lock()
+		enumMappers = saved
+		globalMu.Unlock()
+	})
+}
+
+func TestRegisterEnumMapper(t *testing.T) {
+	withEnumMappers(t)
+
+	RegisterEnumMapper(func(v interface{}) (string, bool) {
+		switch v {
+		case testOrderPending:
+			return "pending", true
+		case testOrderShipped:
+			return "shipped", true
+		}
+		return "", false
+	})
+	// 后注册的映射函数不会覆盖先认领的值
+	RegisterEnumMapper(func(v interface{}) (string, bool) {
+		if _, ok := v.(testOrderStatus); ok {
+			return "unknown", true
+		}
+		return "", false
+	})
+	RegisterEnumMapper(nil)
+
+	status := testOrderShipped
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected string
+	}{
+		{"已映射的值", testOrderShipped, "'shipped'"},
+		{"第一个映射函数未认领", testOrderStatus(9), "'unknown'"},
+		{"指针", &status, "'shipped'"},
+		{"切片", []testOrderStatus{testOrderPending, testOrderShipped}, "'pending','shipped'"},
+		{"普通整数不受影响", 2, "2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Literal(tt.input)
+			if err != nil {
+				t.Fatalf("Literal(%v) error = %v", tt.input, err)
+			}
+			if result != tt.expected {
+				t.Errorf("Literal(%v) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func ExampleRegisterEnumMapper() {
+	type Status int
+	const (
+		StatusPending Status = 1
+		StatusShipped Status = 2
+	)
+	names := map[Status]string{StatusPending: "pending", StatusShipped: "shipped"}
+
+	RegisterEnumMapper(func(v interface{}) (string, bool) {
+		s, ok := v.(Status)
+		if !ok {
+			return "", false
+		}
+		name, ok := names[s]
+		return name, ok
+	})
+
+	sql, _ := Expand("UPDATE orders SET status = ? WHERE id = ?", []interface{}{StatusShipped, 42})
+	fmt.Println(sql)
+	// Output: UPDATE orders SET status = 'shipped' WHERE id = 42
+}
+
+func TestExplainString(t *testing.T) {
+	processor := NewTypeAwareProcessor()
+	processor.RegisterValidator(NameValidator{MaxLength: 12})
+
+	input := "Ｏｒｄｅｒ\t  union select"
+	steps := processor.ExplainString(input, ParamTypeName)
+
+	wantStages := []string{StageNormalize, StageWhitespace, StagePatternReplace, StageTruncate}
+	if len(steps) != len(wantStages) {
+		t.Fatalf("ExplainString(%q) = %+v, want stages %v", input, steps, wantStages)
+	}
+	for i, step := range steps {
+		if step.Stage != wantStages[i] {
+			t.Errorf("steps[%d].Stage = %q, want %q", i, step.Stage, wantStages[i])
+		}
+		if i > 0 && step.Before != steps[i-1].After {
+			t.Errorf("steps[%d].Before = %q, want previous After %q", i, step.Before, steps[i-1].After)
+		}
+	}
+	if steps[0].Before != input || steps[0].After != "Order\t  union select" {
+		t.Errorf("normalize step = %+v", steps[0])
+	}
+	if steps[1].After != "Order union select" {
+		t.Errorf("whitespace step = %+v", steps[1])
+	}
+	if got, want := steps[len(steps)-1].After, processor.ProcessString(input, ParamTypeName); got != want {
+		t.Errorf("last step After = %q, ProcessString = %q", got, want)
+	}
+
+	// 干净的 ASCII 输入也记录完整的阶段
+	if steps := processor.ExplainString("John", ParamTypeGeneric); len(steps) != 4 {
+		t.Errorf("ExplainString(\"John\") = %+v, want 4 steps", steps)
+	}
+
+	// 可选阶段只在开启对应选项时出现
+	processor.RegisterValidator(GenericValidator{BlockStackedQueries: true, FoldHomoglyphs: true})
+	var stages []string
+	for _, step := range processor.ExplainString("a; b", ParamTypeGeneric) {
+		stages = append(stages, step.Stage)
+	}
+	want := []string{StageNormalize, StageWhitespace, StageHomoglyph, StagePatternReplace, StageStackedQueries, StageTruncate}
+	if strings.Join(stages, ",") != strings.Join(want, ",") {
+		t.Errorf("stages = %v, want %v", stages, want)
+	}
+
+	processor.RegisterValidator(upperValidator{})
+	steps = processor.ExplainString("abc", upperValidator{}.GetType())
+	if len(steps) != 1 || steps[0].Stage != StageValidate || steps[0].After != "ABC" {
+		t.Errorf("ExplainString with custom validator = %+v", steps)
+	}
+}
+
+func TestValidateWithInfo(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator interface {
+			ParamValidator
+			ValidateWithInfo(value string) (string, ValidateInfo)
+		}
+		input string
+		want  ValidateInfo
+	}{
+		{"未改写", NewNameValidator(0), "张三", ValidateInfo{OriginalLen: 2}},
+		{"干净的ASCII", NewGenericValidator(0), "hello world", ValidateInfo{OriginalLen: 11}},
+		{"规范化不计入", NewNameValidator(0), "  Ｊｏｈｎ  ", ValidateInfo{OriginalLen: 8}},
+		{"ID截断", NewIDValidator(4), "abcdef", ValidateInfo{Truncated: true, OriginalLen: 6}},
+		{"ID非法字符", NewIDValidator(0), "a'b c", ValidateInfo{OriginalLen: 5, Replacements: 2}},
+		{"危险模式", NewGenericValidator(0), "1 union select 2 -- x --", ValidateInfo{OriginalLen: 24, Replacements: 3}},
+		{"描述截断并替换", NewDescriptionValidator(5), "a -- b", ValidateInfo{Truncated: true, OriginalLen: 6, Replacements: 1}},
+		{"堆叠查询分号", GenericValidator{BlockStackedQueries: true}, "a; b; c", ValidateInfo{OriginalLen: 7, Replacements: 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, info := tt.validator.ValidateWithInfo(tt.input)
+			if want := tt.validator.Validate(tt.input); result != want {
+				t.Errorf("ValidateWithInfo(%q) result = %q, Validate = %q", tt.input, result, want)
+			}
+			if info != tt.want {
+				t.Errorf("ValidateWithInfo(%q) info = %+v, want %+v", tt.input, info, tt.want)
+			}
+		})
+	}
+}
+
+func TestDescriptionKeepComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"双减号保留", "cost -- estimate", "cost -- estimate"},
+		{"块注释保留", "a /* note */ b", "a /* note */ b"},
+		{"数学表达式保留", "x--y */ 2", "x--y */ 2"},
+		{"其他危险模式照常处理", "-- note'; DROP TABLE t", "-- note'; drop_table t"},
+		{"联合查询照常处理", "1 union select 2", "1 union_select 2"},
+	}
+
+	validator := DescriptionValidator{KeepComments: true}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := validator.Validate(tt.input); result != tt.expected {
+				t.Errorf("Validate(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+
+	// 引号内的注释符号只是普通文本
+	processor := NewTypeAwareProcessor()
+	processor.RegisterValidator(validator)
+	if got := processor.ProcessString("a -- b", ParamTypeDescription); QuoteStringFor(got, DialectMySQL) != "'a -- b'" {
+		t.Errorf("ProcessString() = %q, want comment sequence kept", got)
+	}
+
+	for _, rule := range validator.Patterns() {
+		if rule.Pattern == "--" || rule.Pattern == "/*" || rule.Pattern == "*/" || rule.Pattern == "#" {
+			t.Errorf("Patterns() contains comment pattern %q", rule.Pattern)
+		}
+	}
+
+	// 默认仍改写注释符号
+	if got := (DescriptionValidator{}).Validate("cost -- estimate"); got != "cost _- estimate" {
+		t.Errorf("DescriptionValidator{}.Validate() = %q, want comment rewritten", got)
+	}
+}
+
+func TestIDValidatorReplacement(t *testing.T) {
+	tests := []struct {
+		name        string
+		replacement rune
+		input       string
+		expected    string
+	}{
+		{"默认下划线", 0, "project@123", "project_123"},
+		{"删除非法字符", DropIllegalChars, "project@123", "project123"},
+		{"删除注入字符", DropIllegalChars, "1' OR '1'='1", "1OR11"},
+		{"自定义替换字符", '-', "a b", "a-b"},
+		{"非法的替换字符按下划线处理", '\'', "a b", "a_b"},
+		{"全角字符规范化后保留", DropIllegalChars, "ａｂｃ１２３", "abc123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := IDValidator{Replacement: tt.replacement}
+			if result := v.Validate(tt.input); result != tt.expected {
+				t.Errorf("Validate(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+
+	// 删除的字符同样计入改写
+	_, info := IDValidator{Replacement: DropIllegalChars}.ValidateWithInfo("a@b#c")
+	if info.Replacements != 2 {
+		t.Errorf("ValidateWithInfo() Replacements = %d, want 2", info.Replacements)
+	}
+}
+
+// testValuer 返回固定值或错误的 driver.Valuer
+type testValuer struct {
+	v   driver.Value
+	err error
+}
+
+func (tv testValuer) Value() (driver.Value, error) { return tv.v, tv.err }
+
+// TestValuerLiteral 测试 driver.Valuer 返回各种 driver.Value 类型时的渲染
+func TestValuerLiteral(t *testing.T) {
+	ts := time.Date(2024, 3, 5, 6, 7, 8, 900000000, time.UTC)
+	tests := []struct {
+		name     string
+		value    driver.Value
+		dialect  Dialect
+		expected string
+	}{
+		{"string", "it's", DialectMySQL, "'it''s'"},
+		{"string - ANSI", `it's C:\dir`, DialectANSI, `'it''s C:\dir'`},
+		{"int64", int64(-42), DialectMySQL, "-42"},
+		{"float64", 1.5, DialectMySQL, "1.5"},
+		{"bool", true, DialectMySQL, "true"},
+		{"[]byte", []byte("abc"), DialectMySQL, "'abc'"},
+		{"[]byte - Postgres", []byte(`a\b`), DialectPostgres, `'a\b'`},
+		{"time.Time", ts, DialectMySQL, "'2024-03-05 06:07:08'"},
+		{"time.Time - SQLite", ts, DialectSQLite, "'2024-03-05 06:07:08'"},
+		{"nil", nil, DialectMySQL, "NULL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := (&Sanitizer{Dialect: tt.dialect}).Literal(testValuer{v: tt.value})
+			if err != nil {
+				t.Fatalf("Literal(%v) error = %v", tt.value, err)
+			}
+			if result != tt.expected {
+				t.Errorf("Literal(%v) = %q, want %q", tt.value, result, tt.expected)
+			}
+			// 与直接渲染返回值的结果一致
+			if direct, _ := (&Sanitizer{Dialect: tt.dialect}).Literal(tt.value); direct != result {
+				t.Errorf("Literal(Valuer) = %q, Literal(value) = %q", result, direct)
+			}
+		})
+	}
+
+	// 返回的 time.Time 同样使用当前的 TimeLayout
+	defer func(layout string) { TimeLayout = layout }(TimeLayout)
+	TimeLayout = TimeLayoutMicro
+	if result, _ := Literal(testValuer{v: ts}); result != "'2024-03-05 06:07:08.9'" {
+		t.Errorf("Literal(Valuer time.Time) = %q, want TimeLayoutMicro", result)
+	}
+
+	// Value 返回的错误带上类型信息并保留原始错误
+	errBoom := errors.New("boom")
+	_, err := Expand("SELECT ?", []interface{}{testValuer{err: errBoom}})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("Expand() error = %v, want wrapping %v", err, errBoom)
+	}
+	if !strings.Contains(err.Error(), "testValuer") {
+		t.Errorf("Expand() error = %q, want type name", err)
+	}
+}
+
+func TestErrorOnOverflow(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator StrictValidator
+		input     string
+		wantLen   int
+		wantMax   int
+	}{
+		{"ID", IDValidator{MaxLength: 3, ErrorOnOverflow: true}, "abcde", 5, 3},
+		{"名称按字符计", NameValidator{MaxLength: 2, ErrorOnOverflow: true}, "张三丰", 3, 2},
+		{"描述默认长度", DescriptionValidator{ErrorOnOverflow: true}, strings.Repeat("a", DefaultDescriptionMaxLength+7), DefaultDescriptionMaxLength + 7, DefaultDescriptionMaxLength},
+		{"通用", GenericValidator{MaxLength: 4, ErrorOnOverflow: true}, "https://example.com", 19, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.validator.ValidateStrict(tt.input)
+			var lengthErr *LengthError
+			if !errors.As(err, &lengthErr) {
+				t.Fatalf("ValidateStrict(%q) error = %v, want *LengthError", tt.input, err)
+			}
+			if lengthErr.Length != tt.wantLen || lengthErr.MaxLength != tt.wantMax || lengthErr.Type != tt.validator.GetType() {
+				t.Errorf("ValidateStrict(%q) error = %+v, want Length %d MaxLength %d", tt.input, lengthErr, tt.wantLen, tt.wantMax)
+			}
+			if !strings.Contains(err.Error(), fmt.Sprint(tt.wantLen-tt.wantMax)) {
+				t.Errorf("Error() = %q, want overflow amount", err)
+			}
+			// Validate 仍然截断
+			if result := tt.validator.Validate(tt.input); utf8.RuneCountInString(result) != tt.wantMax {
+				t.Errorf("Validate(%q) = %q, want truncated to %d", tt.input, result, tt.wantMax)
+			}
+		})
+	}
+
+	// 未超出限制、未开启选项、不限制长度时不返回错误
+	for _, v := range []StrictValidator{
+		NameValidator{MaxLength: 3, ErrorOnOverflow: true},
+		NameValidator{MaxLength: 2},
+		NameValidator{MaxLength: NoLengthLimit, ErrorOnOverflow: true},
+	} {
+		if _, err := v.ValidateStrict("张三丰"); err != nil {
+			t.Errorf("%+v.ValidateStrict() error = %v", v, err)
+		}
+	}
+
+	// 通过处理器使用
+	processor := NewTypeAwareProcessor()
+	processor.RegisterValidator(IDValidator{MaxLength: 3, ErrorOnOverflow: true})
+	if err := processor.SetMaxLength(ParamTypeID, 2); err != nil {
+		t.Fatalf("SetMaxLength() error = %v", err)
+	}
+	var lengthErr *LengthError
+	if _, err := processor.ProcessStringStrict("abc", ParamTypeID); !errors.As(err, &lengthErr) || lengthErr.MaxLength != 2 {
+		t.Errorf("ProcessStringStrict() error = %v, want *LengthError with MaxLength 2", err)
+	}
+}
+
+func TestUnsupportedTypeError(t *testing.T) {
+	type point struct{ X, Y int }
+	tests := []struct {
+		name  string
+		input interface{}
+		want  string
+	}{
+		{"complex128", complex(1, 2), "complex128"},
+		{"complex64", complex64(complex(1, 2)), "complex64"},
+		{"结构体", point{1, 2}, "sqlhelper.point（底层类型 struct）"},
+		{"map", map[string]int{"a": 1}, "map[string]int"},
+		{"chan", make(chan int), "chan int"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Literal(tt.input)
+			if !errors.Is(err, ErrUnsupportedType) {
+				t.Fatalf("Literal(%v) error = %v, want ErrUnsupportedType", tt.input, err)
+			}
+			msg := err.Error()
+			if !strings.Contains(msg, tt.want) || !strings.Contains(msg, "driver.Valuer") {
+				t.Errorf("Literal(%v) error = %q, want type %q and a hint", tt.input, msg, tt.want)
+			}
+		})
+	}
+
+	// 通过 Expand 返回同样的错误
+	if _, err := Expand("SELECT ?", []interface{}{complex(1, 2)}); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("Expand() error = %v, want ErrUnsupportedType", err)
+	}
+}
+
+// TestSupportedTypes 检查 SupportedTypes 与 literal 的类型分支保持同步，且每个类型都能被渲染
+func TestSupportedTypes(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "sqlhelper.go", nil, 0)
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+	var cases []string
+	ast.Inspect(file, func(n ast.Node) bool {
+		fn, ok := n.(*ast.FuncDecl)
+		if !ok || fn.Name.Name != "literal" || fn.Recv == nil {
+			return true
+		}
+		for _, stmt := range fn.Body.List {
+			if sw, ok := stmt.(*ast.TypeSwitchStmt); ok {
+				for _, clause := range sw.Body.List {
+					for _, expr := range clause.(*ast.CaseClause).List {
+						if s := types.ExprString(expr); s != "nil" {
+							cases = append(cases, s)
+						}
+					}
+				}
+			}
+		}
+		return false
+	})
+
+	// reflect 的类型名与源码写法不同的类型
+	aliases := map[reflect.Type]string{
+		reflect.TypeOf([]byte(nil)):          "[]byte",
+		reflect.TypeOf(json.RawMessage(nil)): "json.RawMessage",
+	}
+	var got []string
+	for _, typ := range SupportedTypes() {
+		name, ok := aliases[typ]
+		if !ok {
+			name = strings.TrimPrefix(typ.String(), "sqlhelper.")
+		}
+		got = append(got, name)
+	}
+	if strings.Join(got, ",") != strings.Join(cases, ",") {
+		t.Errorf("SupportedTypes() = %v\nliteral 的类型分支 = %v", got, cases)
+	}
+
+	for _, typ := range SupportedTypes() {
+		if _, err := Literal(reflect.Zero(typ).Interface()); errors.Is(err, ErrUnsupportedType) {
+			t.Errorf("Literal(%v 零值) error = %v", typ, err)
+		}
+	}
+
+	// 返回副本
+	SupportedTypes()[0] = nil
+	if SupportedTypes()[0] == nil {
+		t.Error("修改 SupportedTypes() 的结果影响了后续调用")
+	}
+}
+
+func TestProcessRunes(t *testing.T) {
+	processor := NewTypeAwareProcessor()
+	inputs := []string{"", "张三", "Ｊｏｈｎ  Ｓｍｉｔｈ", "'; DROP TABLE users; --", "a\tb\nc", "project@123", "İNSERT ınto"}
+	types := []ParamType{ParamTypeGeneric, ParamTypeID, ParamTypeName, ParamTypeDescription, ParamTypeJSON}
+
+	for _, paramType := range types {
+		for _, input := range inputs {
+			want := processor.ProcessString(input, paramType)
+			if got := processor.ProcessRunes([]rune(input), paramType); string(got) != want {
+				t.Errorf("ProcessRunes(%q, %d) = %q, want %q", input, paramType, string(got), want)
+			}
+		}
+	}
+
+	// 非法码点变为 U+FFFD
+	if got := processor.ProcessRunes([]rune{'a', 0xD800, 'b'}, ParamTypeName); string(got) != "a�b" {
+		t.Errorf("ProcessRunes(surrogate) = %q, want %q", string(got), "a�b")
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		escapeChar byte
+		expected   string
+	}{
+		{"无特殊字符", "hello 世界", '\\', "hello 世界"},
+		{"百分号", "100%", '\\', `100\%`},
+		{"下划线", "a_b", '\\', `a\_b`},
+		{"反斜杠转义符本身", `C:\dir`, '\\', `C:\\dir`},
+		{"三种特殊字符", `%_\`, '\\', `\%\_\\`},
+		{"自定义转义符", "50%_off!", '!', "50!%!_off!!"},
+		{"自定义转义符时反斜杠不变", `a\%`, '!', `a\!%`},
+		{"空字符串", "", '\\', ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := EscapeLike(tt.input, tt.escapeChar); result != tt.expected {
+				t.Errorf("EscapeLike(%q, %q) = %q, want %q", tt.input, tt.escapeChar, result, tt.expected)
+			}
+		})
+	}
+
+	// 作为参数展开后 LIKE 模式保持转义
+	result, err := ExpandFor("SELECT * FROM t WHERE name LIKE ? ESCAPE '!'", []interface{}{EscapeLike("50%_off", '!') + "%"}, DialectANSI)
+	if err != nil {
+		t.Fatalf("ExpandFor() error = %v", err)
+	}
+	if want := "SELECT * FROM t WHERE name LIKE '50!%!_off%' ESCAPE '!'"; result != want {
+		t.Errorf("ExpandFor() = %q, want %q", result, want)
+	}
+}
+
+// decodeLiteralFor 按方言的字符串字面量语法解析 lit
+func decodeLiteralFor(lit string, d Dialect) (string, error) {
+	if d.backslashEscapes() {
+		return parseMySQLString(lit)
+	}
+	return parseStandardString(lit)
+}
+
+// FuzzQuoteStringFor 验证任意输入经 QuoteStringFor 转义后，按对应方言的字面量语法解析得到的字节与原输入完全相同
+func FuzzQuoteStringFor(f *testing.F) {
+	seeds := []string{
+		"", `\`, `\'`, `'\`, `\\'`, "''", `\''`, `'\''`, `\%\_`,
+		"a\x00b\x1a\n\r\t\"", "\xbf\\'", "\xff\xfe'--", "İ'",
+	}
+	for _, s := range seeds {
+		f.Add(s)
+	}
+	dialects := []Dialect{DialectMySQL, DialectANSI, DialectPostgres, DialectMySQLNoBackslashEscapes, DialectSQLite}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		for _, d := range dialects {
+			lit := QuoteStringFor(s, d)
+			got, err := decodeLiteralFor(lit, d)
+			if err != nil {
+				t.Fatalf("QuoteStringFor(%q, %v) = %q, parse error = %v", s, d, lit, err)
+			}
+			want := s
+			if d == DialectPostgres {
+				want = strings.ReplaceAll(s, "\x00", "") // PostgreSQL 文本中的空字节被去掉
+			}
+			if got != want {
+				t.Fatalf("QuoteStringFor(%q, %v) = %q, decoded = %q", s, d, lit, got)
+			}
+		}
+	})
+}
+
+// TestQuoteStringExhaustive 对由特殊字符组成的所有短字符串验证转义可逆
+func TestQuoteStringExhaustive(t *testing.T) {
+	alphabet := []string{`\`, `'`, `"`, "\n", "\r", "\t", "\x00", "\x1a", "%", "_", "a", "\xbf", "\xff"}
+	dialects := []Dialect{DialectMySQL, DialectANSI, DialectMySQLNoBackslashEscapes}
+
+	var walk func(prefix string, depth int)
+	walk = func(prefix string, depth int) {
+		for _, d := range dialects {
+			lit := QuoteStringFor(prefix, d)
+			if got, err := decodeLiteralFor(lit, d); err != nil || got != prefix {
+				t.Fatalf("QuoteStringFor(%q, %v) = %q, decoded = %q, err = %v", prefix, d, lit, got, err)
+			}
+		}
+		if depth == 0 {
+			return
+		}
+		for _, c := range alphabet {
+			walk(prefix+c, depth-1)
+		}
+	}
+	walk("", 4)
+}
+
+func TestExpandInsertStruct(t *testing.T) {
+	type Audit struct {
+		CreatedBy string    `db:"created_by"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+	type Versioned struct {
+		Version int `db:"version"`
+	}
+	type base struct {
+		ID     int `db:"id"`
+		secret string
+	}
+	type label string
+	type user struct {
+		ID       int64  `db:"id"`
+		Name     string `db:"name,omitempty"`
+		Email    string
+		Password string `db:"-"`
+		internal string
+		Audit
+		*Versioned
+	}
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		table   string
+		v       interface{}
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "标签、嵌入和跳过的字段",
+			table: "users",
+			v:     user{ID: 1, Name: "O'Neil", Email: "a@b.com", Password: "secret", internal: "x", Audit: Audit{CreatedBy: "admin", CreatedAt: ts}},
+			want:  "INSERT INTO users (id, name, Email, created_by, created_at) VALUES (1, 'O''Neil', 'a@b.com', 'admin', '2024-01-02 03:04:05')",
+		},
+		{
+			name:  "指针和嵌入的指针",
+			table: "app.users",
+			v:     &user{ID: 2, Versioned: &Versioned{Version: 3}},
+			want:  "INSERT INTO app.users (id, name, Email, created_by, created_at, version) VALUES (2, '', '', '', '0001-01-01 00:00:00', 3)",
+		},
+		{
+			name:  "嵌入未导出的结构体",
+			table: "t",
+			v: struct {
+				base
+				Name string `db:"name"`
+			}{base{ID: 1, secret: "s"}, "x"},
+			want: "INSERT INTO t (id, name) VALUES (1, 'x')",
+		},
+		{
+			name:  "嵌入未导出结构体的指针和非结构体类型",
+			table: "t",
+			v: struct {
+				*base
+				label
+				Name string `db:"name"`
+			}{&base{ID: 2}, "l", "x"},
+			want: "INSERT INTO t (id, name) VALUES (2, 'x')",
+		},
+		{
+			name:  "值会被清理",
+			table: "t",
+			v:     struct{ Note string }{"'; DROP TABLE users;--"},
+			want:  "INSERT INTO t (Note) VALUES ('''; drop_table users;__')",
+		},
+		{
+			name:  "嵌入的 time.Time 作为单个值",
+			table: "t",
+			v: struct {
+				time.Time `db:"at"`
+				sql.NullString
+			}{ts, sql.NullString{}},
+			want: "INSERT INTO t (at, NullString) VALUES ('2024-01-02 03:04:05', NULL)",
+		},
+		{name: "非法表名", table: "t; DROP", v: user{}, wantErr: true},
+		{name: "表名含注释", table: "app.t--", v: user{}, wantErr: true},
+		{name: "列名含井号", table: "t", v: struct {
+			A int `db:"a#"`
+		}{}, wantErr: true},
+		{name: "非法列名", table: "t", v: struct {
+			A int `db:"a b"`
+		}{}, wantErr: true},
+		{name: "列名重复", table: "t", v: struct {
+			A int `db:"x"`
+			B int `db:"x"`
+		}{}, wantErr: true},
+		{name: "没有列", table: "t", v: struct{ a int }{}, wantErr: true},
+		{name: "不是结构体", table: "t", v: 1, wantErr: true},
+		{name: "nil 指针", table: "t", v: (*user)(nil), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandInsertStruct(tt.table, tt.v)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExpandInsertStruct() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ExpandInsertStruct() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSafeOrderBy(t *testing.T) {
+	allowed := map[string]string{
+		"name":    "u.name",
+		"created": "u.created_at",
+		"score":   "COALESCE(s.score, 0)",
+	}
+	tests := []struct {
+		name    string
+		cols    []string
+		want    string
+		wantErr bool
+	}{
+		{"单列", []string{"name"}, "u.name", false},
+		{"多列带方向", []string{"name desc", "created ASC", "score"}, "u.name DESC, u.created_at ASC, COALESCE(s.score, 0)", false},
+		{"多余空白", []string{"  name \t Desc "}, "u.name DESC", false},
+		{"空列表", nil, "", false},
+		{"未知的键", []string{"password"}, "", true},
+		{"注入的键", []string{"name; DROP TABLE users"}, "", true},
+		{"键区分大小写", []string{"NAME"}, "", true},
+		{"非法方向", []string{"name DESC, (SELECT 1)"}, "", true},
+		{"注入的方向", []string{"name sleep(5)"}, "", true},
+		{"空项", []string{"name", ""}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SafeOrderBy(tt.cols, allowed)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SafeOrderBy(%q) error = %v, wantErr %v", tt.cols, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("SafeOrderBy(%q) = %q, want %q", tt.cols, got, tt.want)
+			}
+		})
+	}
+
+	// 未知的键可以用 errors.Is 判断
+	if _, err := SafeOrderBy([]string{"password desc"}, allowed); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("SafeOrderBy() error = %v, want ErrNotAllowed", err)
+	}
+}
+
+func TestCleanIdentifierList(t *testing.T) {
+	allowed := map[string]bool{
+		"id":       true,
+		"name":     true,
+		"u.email":  true,
+		"order":    true,
+		"a`b":      true,
+		"password": false,
+	}
+	tests := []struct {
+		name    string
+		cols    []string
+		dialect Dialect
+		want    string
+		wantErr bool
+	}{
+		{"MySQL", []string{"id", "name"}, DialectMySQL, "`id`, `name`", false},
+		{"带表名的列", []string{"u.email"}, DialectMySQL, "`u`.`email`", false},
+		{"保留字", []string{"order"}, DialectPostgres, `"order"`, false},
+		{"ANSI", []string{"id", "u.email"}, DialectANSI, `"id", "u"."email"`, false},
+		{"SQLServer", []string{"id", "u.email"}, DialectSQLServer, "[id], [u].[email]", false},
+		{"引号字符转义", []string{"a`b"}, DialectMySQL, "`a``b`", false},
+		{"未允许的列", []string{"id", "secret"}, DialectMySQL, "", true},
+		{"值为 false 的列", []string{"password"}, DialectMySQL, "", true},
+		{"注入的列名", []string{"id, (SELECT password FROM users)"}, DialectMySQL, "", true},
+		{"区分大小写", []string{"ID"}, DialectMySQL, "", true},
+		{"空列表", nil, DialectMySQL, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CleanIdentifierList(tt.cols, allowed, tt.dialect)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CleanIdentifierList(%q) error = %v, wantErr %v", tt.cols, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CleanIdentifierList(%q) = %q, want %q", tt.cols, got, tt.want)
+			}
+		})
+	}
+
+	// 未允许的列可以用 errors.Is 判断
+	if _, err := CleanIdentifierList([]string{"password"}, allowed, DialectMySQL); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("CleanIdentifierList() error = %v, want ErrNotAllowed", err)
+	}
+}
+
+func TestExpandPooledBufferNoLeak(t *testing.T) {
+	// 先展开一条很长的语句，再展开短语句，复用的缓冲区不能残留上一次的内容
+	long, err := Expand("SELECT ?", []interface{}{strings.Repeat("x", 1000)})
+	if err != nil {
+		t.Fatalf("Expand() error = %v", err)
+	}
+	short, err := Expand("SELECT ?", []interface{}{1})
+	if err != nil {
+		t.Fatalf("Expand() error = %v", err)
+	}
+	if short != "SELECT 1" {
+		t.Errorf("Expand() = %q, want %q", short, "SELECT 1")
+	}
+	// 之前返回的结果不能被之后的调用改写
+	if want := "SELECT '" + strings.Repeat("x", 1000) + "'"; long != want {
+		t.Errorf("Expand() 结果被后续调用改写, got len %d", len(long))
+	}
+
+	// 出错时放回的缓冲区同样要清空
+	if _, err := Expand("SELECT ?, ?", []interface{}{"abc", func() {}}); err == nil {
+		t.Fatal("Expand() 期望返回不支持类型的错误")
+	}
+	if got, _ := Expand("SELECT ?", []interface{}{2}); got != "SELECT 2" {
+		t.Errorf("Expand() after error = %q, want %q", got, "SELECT 2")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				want := fmt.Sprintf("SELECT %d, %d", i, j)
+				if got, err := Expand("SELECT ?, ?", []interface{}{i, j}); err != nil || got != want {
+					t.Errorf("Expand() = %q, %v, want %q", got, err, want)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestKeepOriginal(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator ParamValidator
+		input     string
+		expected  string
+	}{
+		{"名称保留多余空格", NameValidator{KeepOriginal: true}, "  John   Smith ", "  John   Smith "},
+		{"名称保留全角字符", NameValidator{KeepOriginal: true}, "ＡＢＣ公司", "ＡＢＣ公司"},
+		{"名称保留制表符", NameValidator{KeepOriginal: true}, "a\tb", "a\tb"},
+		{"名称命中危险模式照常处理", NameValidator{KeepOriginal: true}, "  1 union select 2", "1 union_select 2"},
+		{"全角危险模式照常处理", NameValidator{KeepOriginal: true}, "ｕｎｉｏｎ　ｓｅｌｅｃｔ", "union_select"},
+		{"名称超长照常截断", NameValidator{MaxLength: 3, KeepOriginal: true}, "ａ b c", "a b"},
+		{"默认关闭", NameValidator{}, "  John   Smith ", "John Smith"},
+		{"描述保留回车换行", DescriptionValidator{KeepOriginal: true}, "第一行\r\n第二行", "第一行\r\n第二行"},
+		{"描述命中危险模式照常处理", DescriptionValidator{KeepOriginal: true}, "a\r\n-- b", "a\n_- b"},
+		{"去除HTML时不保留含标签的输入", DescriptionValidator{StripHTML: true, KeepOriginal: true}, "<b>粗体</b>\r\n", "粗体\n"},
+		{"去除HTML时保留不含标签的输入", DescriptionValidator{StripHTML: true, KeepOriginal: true}, "纯文本\r\n", "纯文本\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.validator.Validate(tt.input); result != tt.expected {
+				t.Errorf("Validate(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+
+			// 严格模式下干净的输入同样原样返回
+			if sv, ok := tt.validator.(StrictValidator); ok {
+				if got, err := sv.ValidateStrict(tt.input); err == nil && got != tt.expected {
+					t.Errorf("ValidateStrict(%q) = %q, want %q", tt.input, got, tt.expected)
+				}
+			}
+
+			// ExplainString 最后一步与 ProcessString 的结果一致
+			processor := NewTypeAwareProcessor()
+			processor.RegisterValidator(tt.validator)
+			steps := processor.ExplainString(tt.input, tt.validator.GetType())
+			if got := steps[len(steps)-1].After; got != tt.expected {
+				t.Errorf("ExplainString(%q) 最后一步 = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+
+	// 保留原值后仍然正确加引号
+	processor := NewTypeAwareProcessor()
+	processor.RegisterValidator(NameValidator{KeepOriginal: true})
+	if got := processor.ProcessString("O'Brien  Ltd", ParamTypeName); QuoteStringFor(got, DialectPostgres) != "'O''Brien  Ltd'" {
+		t.Errorf("ProcessString() = %q, want original kept", got)
+	}
+}
+
+func TestPreserveCase(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator ParamValidator
+		input     string
+		expected  string
+	}{
+		{"通用混合大小写", GenericValidator{PreserveCase: true}, "project' UnIoN sElEcT 1", "project' UnIoN_sElEcT 1"},
+		{"通用全大写", GenericValidator{PreserveCase: true}, "1; DROP TABLE users", "1; DROP_TABLE users"},
+		{"通用存储过程", GenericValidator{PreserveCase: true}, "EXEC Xp_CmdShell", "EXEC Xp_Cmd_Shell"},
+		{"通用默认小写", GenericValidator{}, "project' UnIoN sElEcT 1", "project' union_select 1"},
+		{"名称插入字符的替换", NameValidator{PreserveCase: true}, "SubString(x)", "_SubString_(x)"},
+		{"名称布尔注入", NameValidator{PreserveCase: true}, "a OR b", "a_OR_b"},
+		{"名称多处命中", NameValidator{PreserveCase: true}, "Union Select 1 union select 2", "Union_Select 1 union_select 2"},
+		{"全角输入按规范化后的大小写", NameValidator{PreserveCase: true}, "ＵＮＩＯＮ ｓｅｌｅｃｔ", "UNION_select"},
+		{"折叠后长度变化时退回小写", NameValidator{PreserveCase: true}, "UNİON SELECT", "union_select"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.validator.Validate(tt.input); result != tt.expected {
+				t.Errorf("Validate(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+
+	caseTests := []struct {
+		repl, pattern, span, expected string
+	}{
+		{"union_select", "union select", "UnIoN sElEcT", "UnIoN_sElEcT"},
+		{"_ascii_", "ascii", "ASCII", "_ASCII_"},
+		{"'; drop_table", "'; drop table", "'; Drop Table", "'; Drop_Table"},
+		{"__", "--", "--", "__"},
+		{"union_select", "union select", "unİon select", "union_select"},
+	}
+	for _, tt := range caseTests {
+		if result := matchCase(tt.repl, tt.pattern, tt.span); result != tt.expected {
+			t.Errorf("matchCase(%q, %q, %q) = %q, want %q", tt.repl, tt.pattern, tt.span, result, tt.expected)
+		}
+	}
+}
+
+func TestDisablePatterns(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator ParamValidator
+		input     string
+		expected  string
+	}{
+		{"禁用concat", NameValidator{}.DisablePatterns("concat"), "Concatenation Corp", "Concatenation Corp"},
+		{"默认仍替换concat", NameValidator{}, "Concatenation Corp", "_concat_enation Corp"},
+		{"大小写不敏感", NameValidator{}.DisablePatterns("SUBSTRING", "Ascii"), "Substring ascii", "Substring ascii"},
+		{"其他模式照常处理", NameValidator{}.DisablePatterns("concat"), "concat union select", "concat union_select"},
+		{"多次调用累加", NameValidator{}.DisablePatterns("concat").DisablePatterns("delay"), "concat delay", "concat delay"},
+		{"不存在的模式被忽略", NameValidator{}.DisablePatterns("no such pattern"), "a--b", "a__b"},
+		{"通用验证器", GenericValidator{}.DisablePatterns("--"), "a -- b; DROP TABLE t", "a -- b; drop_table t"},
+		{"描述验证器", DescriptionValidator{}.DisablePatterns("union select"), "union select -- x", "union select _- x"},
+		{"与KeepComments组合", DescriptionValidator{KeepComments: true}.DisablePatterns("union select"), "union select -- x", "union select -- x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.validator.Validate(tt.input); result != tt.expected {
+				t.Errorf("Validate(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+
+			// 严格模式同样不再检测被禁用的模式
+			if tt.input == tt.expected {
+				if result, err := tt.validator.(StrictValidator).ValidateStrict(tt.input); err != nil || result != tt.expected {
+					t.Errorf("ValidateStrict(%q) = %q, %v, want %q", tt.input, result, err, tt.expected)
+				}
+			}
+		})
+	}
+
+	// Patterns 反映禁用后的列表，原验证器不受影响
+	base := NameValidator{}
+	disabled := base.DisablePatterns("concat")
+	for _, rule := range disabled.Patterns() {
+		if rule.Pattern == "concat" {
+			t.Errorf("Patterns() 仍包含被禁用的模式 %q", rule.Pattern)
+		}
+	}
+	if got, want := len(disabled.Patterns()), len(base.Patterns())-1; got != want {
+		t.Errorf("len(Patterns()) = %d, want %d", got, want)
+	}
+
+	// 注册到处理器后 ProcessString 和 ProcessBytes 的结果一致
+	processor := NewTypeAwareProcessor()
+	processor.RegisterValidator(disabled)
+	for _, input := range []string{"Concatenation Corp", "concat union select"} {
+		want := processor.ProcessString(input, ParamTypeName)
+		if got := processor.ProcessBytes([]byte(input), ParamTypeName); string(got) != want {
+			t.Errorf("ProcessBytes(%q) = %q, want %q", input, got, want)
+		}
+	}
+	if got := processor.ProcessString("Concatenation Corp", ParamTypeName); got != "Concatenation Corp" {
+		t.Errorf("ProcessString() = %q, want unchanged", got)
+	}
+
+	// 禁用模式后验证器仍可比较，禁用同样模式（不论顺序、大小写）的验证器相等
+	if (GenericValidator{}).DisablePatterns("--") == (GenericValidator{}) {
+		t.Error("禁用模式的验证器与零值相等")
+	}
+	a, b := NameValidator{}.DisablePatterns("concat", "delay"), NameValidator{}.DisablePatterns("DELAY").DisablePatterns("concat", "delay")
+	if a != b {
+		t.Errorf("%+v != %+v", a, b)
+	}
+	var x, y ParamValidator = DescriptionValidator{}.DisablePatterns("#"), DescriptionValidator{}.DisablePatterns("#")
+	if x != y {
+		t.Error("持有相同验证器的 ParamValidator 接口不相等")
+	}
+
+	// 禁用模式不增加 Validate 的分配次数，过滤后的规则不在每次调用时重建
+	validators := []struct {
+		plain, disabled ParamValidator
+	}{
+		{GenericValidator{}, GenericValidator{}.DisablePatterns("--")},
+		{NameValidator{}, NameValidator{}.DisablePatterns("concat")},
+		{DescriptionValidator{}, DescriptionValidator{}.DisablePatterns("#")},
+	}
+	for _, v := range validators {
+		base := testing.AllocsPerRun(100, func() { v.plain.Validate("hello world") })
+		got := testing.AllocsPerRun(100, func() { v.disabled.Validate("hello world") })
+		if got > base {
+			t.Errorf("%T 禁用模式后分配次数 = %v, 未禁用时 = %v", v.disabled, got, base)
+		}
+	}
+}
+
+func TestRegisterType(t *testing.T) {
+	defer SetGlobalProcessor(nil)
+	defer SetGlobalInferrer(nil)
+	SetGlobalProcessor(nil)
+	SetGlobalInferrer(nil)
+
+	skuType := NewParamType()
+	RegisterType(skuType, prefixValidator{paramType: skuType, prefix: "sku_"}, func(value string) bool {
+		return strings.HasPrefix(value, "SKU-")
+	})
+
+	if paramType, sanitized := InspectString("SKU-001"); paramType != skuType || sanitized != "sku_SKU-001" {
+		t.Errorf("InspectString() = %d, %q, want %d, %q", paramType, sanitized, skuType, "sku_SKU-001")
+	}
+	got, err := Expand("SELECT * FROM items WHERE sku = ? AND name = ?", []interface{}{"SKU-001", "北京项目"})
+	if err != nil {
+		t.Fatalf("Expand() error = %v", err)
+	}
+	if want := "SELECT * FROM items WHERE sku = 'sku_SKU-001' AND name = '北京项目'"; got != want {
+		t.Errorf("Expand() = %q, want %q", got, want)
+	}
+
+	// 后注册的规则排在后面，先注册的规则优先
+	RegisterType(ParamTypeDescription, DescriptionValidator{}, func(value string) bool {
+		return strings.HasPrefix(value, "SKU-") || strings.HasPrefix(value, "NOTE:")
+	})
+	if paramType, _ := InspectString("SKU-002"); paramType != skuType {
+		t.Errorf("InspectString(SKU-002) = %d, want %d", paramType, skuType)
+	}
+	if paramType, _ := InspectString("NOTE:x"); paramType != ParamTypeDescription {
+		t.Errorf("InspectString(NOTE:x) = %d, want ParamTypeDescription", paramType)
+	}
+
+	// 不带推断规则时只注册验证器
+	RegisterType(ParamTypeName, NameValidator{KeepOriginal: true}, nil)
+	if paramType, sanitized := InspectString("北京  项目"); paramType != ParamTypeName || sanitized != "北京  项目" {
+		t.Errorf("InspectString() = %d, %q, want original name kept", paramType, sanitized)
+	}
+
+	// 类型不一致时 panic
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("RegisterType() 类型不一致时期望 panic")
+			}
+		}()
+		RegisterType(ParamTypeName, GenericValidator{}, nil)
+	}()
+
+	// 恢复默认推断器后规则不再生效
+	SetGlobalInferrer(nil)
+	if paramType, _ := InspectString("SKU-001"); paramType == skuType {
+		t.Errorf("InspectString() after reset = %d, want built-in inference", paramType)
+	}
+
+	// 推断器也可以直接通过 InferOptions.Rules 配置
+	opts := DefaultInferOptions()
+	opts.Rules = []InferRule{{Type: skuType, Match: func(value string) bool { return strings.HasPrefix(value, "SKU-") }}}
+	if got := NewTypeInferrer(opts).InferType("SKU-9"); got != skuType {
+		t.Errorf("InferType() = %d, want %d", got, skuType)
+	}
+}
+
+func TestHashCommentPattern(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator ParamValidator
+		input     string
+		expected  string
+	}{
+		{"通用", GenericValidator{}, "value # comment", "value _# comment"},
+		{"通用全角", GenericValidator{}, "value ＃ comment", "value _# comment"},
+		{"名称", NameValidator{}, "value # comment", "value _# comment"},
+		{"描述", DescriptionValidator{}, "line1 #comment\nline2", "line1 _#comment\nline2"},
+		{"描述保留注释时保留话题标签", DescriptionValidator{KeepComments: true}, "#新品 上市 -- 限时", "#新品 上市 -- 限时"},
+		{"描述只保留话题标签", DescriptionValidator{}.DisablePatterns("#"), "#新品 上市 -- 限时", "#新品 上市 _- 限时"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.validator.Validate(tt.input); result != tt.expected {
+				t.Errorf("Validate(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+
+	// 严格模式下 # 被识别为危险模式
+	for _, v := range []StrictValidator{GenericValidator{}, NameValidator{}, DescriptionValidator{}} {
+		_, err := v.ValidateStrict("a # b")
+		var injErr *InjectionError
+		if !errors.As(err, &injErr) || injErr.Pattern != "#" || injErr.Offset != 2 {
+			t.Errorf("%T.ValidateStrict() error = %v, want # at 2", v, err)
+		}
+	}
+	if _, err := (DescriptionValidator{KeepComments: true}).ValidateStrict("#tag"); err != nil {
+		t.Errorf("ValidateStrict(KeepComments) error = %v, want nil", err)
+	}
+}
+
+func TestFoldedStringReplace(t *testing.T) {
+	inputs := []string{
+		"",
+		"x' or '1'='1 union select ascii(substring(concat(a,b),1,1)) -- /* */ # waitfor delay; drop table t",
+		"UNİON SELECT İİ--ß--ẞ",
+		"KLAẞE straße Maß-Sache",
+		"\xff\xfe'--\xe4\xbd--",
+		"北京 Union Select 项目 -- 备注 #",
+		"ＵＮＩＯＮ ｓｅｌｅｃｔ",
+		"aı--ıb",
+	}
+	patternSets := [][]PatternRule{genericPatterns, namePatterns, descriptionPatterns,
+		{{"ss", "__"}, {"i", "İ"}, {"--", "—"}}}
+
+	for _, input := range inputs {
+		for _, patterns := range patternSets {
+			for _, keepCase := range []bool{false, true} {
+				f := newFoldedString(input)
+				for _, rule := range patterns {
+					want := strings.Count(f.lower, rule.Pattern)
+					var n int
+					f, n = f.replace(rule.Pattern, rule.Replacement, keepCase)
+					if n != want {
+						t.Errorf("replace(%q, %q) n = %d, want %d", input, rule.Pattern, n, want)
+					}
+					// 同步更新的折叠形式和偏移与重新折叠的结果一致
+					lower, offsets := foldWithOffsets(f.s)
+					if f.lower != lower {
+						t.Fatalf("replace(%q, %q) lower = %q, want %q", input, rule.Pattern, f.lower, lower)
+					}
+					for i := 0; i <= len(lower); i++ {
+						if offsetIn(f.offsets, i) != offsetIn(offsets, i) {
+							t.Fatalf("replace(%q, %q) offset[%d] = %d, want %d", input, rule.Pattern, i, offsetIn(f.offsets, i), offsetIn(offsets, i))
+						}
+					}
+				}
+			}
+		}
+	}
+}
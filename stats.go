@@ -0,0 +1,104 @@
+package sqlhelper
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// TypeStats 一类参数的处理统计
+type TypeStats struct {
+	Processed    int64 // 处理的字符串个数
+	Replacements int64 // 危险模式、非法字符和分号被替换的处数，同 ValidateInfo.Replacements
+	Truncations  int64 // 因超出长度限制被截断的字符串个数
+	Rejections   int64 // 严格模式下被拒绝（返回 error）的字符串个数
+}
+
+// ProcessorStats TypeAwareProcessor.Stats 返回的统计快照
+type ProcessorStats struct {
+	Total  TypeStats               // 所有参数类型的合计
+	ByType map[ParamType]TypeStats // 按参数类型分别统计
+}
+
+// EnableStats 开启处理统计，之后 ProcessString、ProcessBytes、ProcessRunes、ProcessStringStrict
+// （以及经由它们的 Expand 等，包括 WithStrict）都会累加计数。严格模式只记录处理个数和被拒绝的个数，
+// 通过检查的字符串不计替换和截断，可用于发现攻击流量的突增。未开启时不产生任何额外开销；重复调用不会清零已有计数
+func (tap *TypeAwareProcessor) EnableStats() {
+	tap.stats.CompareAndSwap(nil, &statsCollector{})
+}
+
+// Stats 返回当前统计的快照，未开启统计时返回零值
+// 各计数器独立地原子更新，并发处理时快照中的不同计数之间可能不完全一致
+func (tap *TypeAwareProcessor) Stats() ProcessorStats {
+	c := tap.stats.Load()
+	if c == nil {
+		return ProcessorStats{}
+	}
+	stats := ProcessorStats{ByType: make(map[ParamType]TypeStats)}
+	c.byType.Range(func(key, value interface{}) bool {
+		ts := value.(*typeCounters).snapshot()
+		stats.ByType[key.(ParamType)] = ts
+		stats.Total.Processed += ts.Processed
+		stats.Total.Replacements += ts.Replacements
+		stats.Total.Truncations += ts.Truncations
+		stats.Total.Rejections += ts.Rejections
+		return true
+	})
+	return stats
+}
+
+// statsCollector 按参数类型累加的计数器
+type statsCollector struct {
+	byType sync.Map // ParamType -> *typeCounters
+}
+
+type typeCounters struct {
+	processed    atomic.Int64
+	replacements atomic.Int64
+	truncations  atomic.Int64
+	rejections   atomic.Int64
+}
+
+// record 记录一次处理的结果，c 为 nil（未开启统计）时不做任何事
+func (c *statsCollector) record(paramType ParamType, info ValidateInfo) {
+	if c == nil {
+		return
+	}
+	counters := c.counters(paramType)
+	counters.processed.Add(1)
+	if info.Replacements > 0 {
+		counters.replacements.Add(int64(info.Replacements))
+	}
+	if info.Truncated {
+		counters.truncations.Add(1)
+	}
+}
+
+// recordStrict 记录一次严格模式处理，err 非 nil 时计为被拒绝；c 为 nil 时不做任何事
+func (c *statsCollector) recordStrict(paramType ParamType, err error) {
+	if c == nil {
+		return
+	}
+	counters := c.counters(paramType)
+	counters.processed.Add(1)
+	if err != nil {
+		counters.rejections.Add(1)
+	}
+}
+
+// counters 返回 paramType 的计数器，不存在时创建
+func (c *statsCollector) counters(paramType ParamType) *typeCounters {
+	v, ok := c.byType.Load(paramType)
+	if !ok {
+		v, _ = c.byType.LoadOrStore(paramType, &typeCounters{})
+	}
+	return v.(*typeCounters)
+}
+
+func (t *typeCounters) snapshot() TypeStats {
+	return TypeStats{
+		Processed:    t.processed.Load(),
+		Replacements: t.replacements.Load(),
+		Truncations:  t.truncations.Load(),
+		Rejections:   t.rejections.Load(),
+	}
+}
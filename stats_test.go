@@ -0,0 +1,105 @@
+package sqlhelper
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestProcessorStats(t *testing.T) {
+	processor := NewTypeAwareProcessor()
+	processor.RegisterValidator(NameValidator{MaxLength: 5})
+
+	// 未开启时不统计
+	processor.ProcessString("x", ParamTypeName)
+	if stats := processor.Stats(); stats.Total != (TypeStats{}) || stats.ByType != nil {
+		t.Errorf("Stats() before EnableStats = %+v, want zero", stats)
+	}
+
+	processor.EnableStats()
+	processor.ProcessString("张三", ParamTypeName)
+	processor.ProcessString("1 union select 2 -- x", ParamTypeName) // 两处替换并截断
+	processor.ProcessString("a@b", ParamTypeID)                     // 一处非法字符
+	processor.ProcessBytes([]byte("clean"), ParamTypeGeneric)       // 快速路径同样计数
+	processor.ProcessRunes([]rune("a; b"), ParamTypeGeneric)
+	processor.ProcessString("{}", ParamTypeJSON) // 非内置的 traced 验证器只计处理个数
+
+	stats := processor.Stats()
+	want := map[ParamType]TypeStats{
+		ParamTypeName:    {Processed: 2, Replacements: 2, Truncations: 1},
+		ParamTypeID:      {Processed: 1, Replacements: 1},
+		ParamTypeGeneric: {Processed: 2},
+		ParamTypeJSON:    {Processed: 1},
+	}
+	for paramType, w := range want {
+		if got := stats.ByType[paramType]; got != w {
+			t.Errorf("Stats().ByType[%d] = %+v, want %+v", paramType, got, w)
+		}
+	}
+	if wantTotal := (TypeStats{Processed: 6, Replacements: 3, Truncations: 1}); stats.Total != wantTotal {
+		t.Errorf("Stats().Total = %+v, want %+v", stats.Total, wantTotal)
+	}
+
+	// 重复开启不清零
+	processor.EnableStats()
+	if got := processor.Stats().Total.Processed; got != 6 {
+		t.Errorf("Processed after second EnableStats = %d, want 6", got)
+	}
+}
+
+func TestProcessorStatsConcurrent(t *testing.T) {
+	processor := NewTypeAwareProcessor()
+	processor.EnableStats()
+
+	const goroutines, perGoroutine = 8, 100
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perGoroutine; j++ {
+				processor.ProcessString("x union select y", ParamTypeGeneric)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got := processor.Stats().ByType[ParamTypeGeneric]
+	if got.Processed != goroutines*perGoroutine || got.Replacements != goroutines*perGoroutine {
+		t.Errorf("Stats() = %+v, want %d processed and replacements", got, goroutines*perGoroutine)
+	}
+}
+
+func TestProcessorStatsStrict(t *testing.T) {
+	processor := NewTypeAwareProcessor()
+	processor.EnableStats()
+
+	if _, err := processor.ProcessStringStrict("张三", ParamTypeName); err != nil {
+		t.Fatalf("ProcessStringStrict() error = %v", err)
+	}
+	if _, err := processor.ProcessStringStrict("1 union select 2", ParamTypeName); err == nil {
+		t.Fatal("ProcessStringStrict() error = nil")
+	}
+	if got, want := processor.Stats().ByType[ParamTypeName], (TypeStats{Processed: 2, Rejections: 1}); got != want {
+		t.Errorf("Stats().ByType[Name] = %+v, want %+v", got, want)
+	}
+
+	// Expand 的严格模式同样计数
+	s := &Sanitizer{Processor: processor}
+	if _, err := s.Expand("SELECT ?, ?", []interface{}{"abc", "x; DROP TABLE t"}, WithStrict()); err == nil {
+		t.Fatal("Sanitizer.Expand(WithStrict()) error = nil")
+	}
+	if got := processor.Stats().Total; got.Processed != 4 || got.Rejections != 2 {
+		t.Errorf("Stats().Total = %+v, want 4 processed and 2 rejections", got)
+	}
+}
+
+func TestProcessorStatsDisabledNoAlloc(t *testing.T) {
+	processor := NewTypeAwareProcessor()
+	input := "hello world"
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = processor.ProcessString(input, ParamTypeGeneric)
+	})
+	if allocs != 0 {
+		t.Errorf("ProcessString() allocs = %v, want 0 when stats are disabled", allocs)
+	}
+}
@@ -0,0 +1,44 @@
+package sqlhelper
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// HexBytes 以十六进制字面量渲染的二进制数据，不经过任何文本清理
+// MySQL 下渲染为 X'48656c6c6f'，其他方言见 HexLiteralFor
+type HexBytes []byte
+
+// BinaryBytes 以 MySQL 二进制字符串 _binary'...' 渲染的二进制数据，用于 BINARY、VARBINARY、BLOB 列
+// 只按字节转义引号、反斜杠等，不经过任何文本清理，非 UTF-8 字节原样保留
+// MySQL 以外的方言没有 _binary 前缀，渲染为与 HexBytes 相同的十六进制字面量
+type BinaryBytes []byte
+
+// JSONValue 写入 JSON 列的对象，渲染时先用 encoding/json 序列化，再作为 JSON 字符串字面量加引号
+// 结果经过 JSON 验证器，只做引号转义，不会被合并空白或改写危险模式，例如 DialectANSI 下 JSONValue{"k": "v"} 渲染为 '{"k":"v"}'
+// nil 渲染为 NULL；含有无法序列化的值（如 chan、func）时返回 error
+type JSONValue map[string]interface{}
+
+// TypedList 指定参数类型的字符串列表，用于 IN 子句
+// 展开时每个元素都跳过类型推断，直接用 Type 对应的验证器清理并加引号，以逗号连接；空列表渲染为 NULL
+// 例如 TypedList{Type: ParamTypeID, Values: ids}
+type TypedList struct {
+	Type   ParamType
+	Values []string
+}
+
+// Numeric 十进制数值的文本形式，按原样渲染为不带引号的数值字面量，用于金额等需要精确表示的值
+// 例如 Numeric(d.String()) 把 decimal.Decimal 渲染为 12345.6789，避免经过 float64 损失精度
+// 渲染前校验是否符合数值语法（可选符号、整数和小数部分、可选指数），不符合时返回 error
+type Numeric string
+
+// numericRe Numeric 允许的数值语法
+var numericRe = regexp.MustCompile(`^[+-]?(?:[0-9]+(?:\.[0-9]*)?|\.[0-9]+)(?:[eE][+-]?[0-9]+)?$`)
+
+// numericLiteral 校验并返回 n 的文本
+func numericLiteral(n Numeric) (string, error) {
+	if !numericRe.MatchString(string(n)) {
+		return "", fmt.Errorf("Numeric %q 不是合法的数值", string(n))
+	}
+	return string(n), nil
+}
@@ -0,0 +1,308 @@
+package sqlhelper
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// parseMySQLString 按 MySQL 默认模式的字符串字面量语法解析 lit，返回其表示的原始字节
+// lit 必须恰好是一个完整的单引号字面量
+func parseMySQLString(lit string) (string, error) {
+	if len(lit) < 2 || lit[0] != '\'' {
+		return "", errors.New("not a quoted literal")
+	}
+	var buf strings.Builder
+	for i := 1; i < len(lit); i++ {
+		switch c := lit[i]; c {
+		case '\\':
+			if i+1 >= len(lit) {
+				return "", errors.New("dangling backslash")
+			}
+			i++
+			switch e := lit[i]; e {
+			case '0':
+				buf.WriteByte(0)
+			case 'b':
+				buf.WriteByte('\b')
+			case 'n':
+				buf.WriteByte('\n')
+			case 'r':
+				buf.WriteByte('\r')
+			case 't':
+				buf.WriteByte('\t')
+			case 'Z':
+				buf.WriteByte(0x1a)
+			case '%', '_':
+				buf.WriteByte('\\')
+				buf.WriteByte(e)
+			default:
+				buf.WriteByte(e)
+			}
+		case '\'':
+			if i+1 < len(lit) && lit[i+1] == '\'' {
+				buf.WriteByte('\'')
+				i++
+				continue
+			}
+			if i != len(lit)-1 {
+				return "", errors.New("literal closed before end")
+			}
+			return buf.String(), nil
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return "", errors.New("unterminated literal")
+}
+
+func TestHexBytesLiteral(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    HexBytes
+		dialect  Dialect
+		expected string
+	}{
+		{"MySQL", HexBytes("Hello"), DialectMySQL, "X'48656c6c6f'"},
+		{"MySQL - 空", HexBytes{}, DialectMySQL, "X''"},
+		{"ANSI", HexBytes{0x00, 0xff}, DialectANSI, "X'00ff'"},
+		{"Postgres", HexBytes("Hi"), DialectPostgres, `'\x4869'::bytea`},
+		{"SQLServer", HexBytes("Hi"), DialectSQLServer, "0x4869"},
+		{"Oracle", HexBytes("Hi"), DialectOracle, "HEXTORAW('4869')"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := literalConfig{dialect: tt.dialect}.literal(tt.input)
+			if err != nil {
+				t.Fatalf("literal(%v) error = %v", tt.input, err)
+			}
+			if result != tt.expected {
+				t.Errorf("literal(%v) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHexBytesRoundTrip(t *testing.T) {
+	// 包含引号、反斜杠、空字节和非 UTF-8 字节的二进制数据
+	data := HexBytes("\x00\xff'\\\"-- DROP TABLE users;\x1a\x80")
+	result, err := Literal(data)
+	if err != nil {
+		t.Fatalf("Literal() error = %v", err)
+	}
+	if !strings.HasPrefix(result, "X'") || !strings.HasSuffix(result, "'") {
+		t.Fatalf("Literal() = %q, want X'...'", result)
+	}
+	decoded, err := hex.DecodeString(result[2 : len(result)-1])
+	if err != nil {
+		t.Fatalf("hex.DecodeString(%q) error = %v", result, err)
+	}
+	if string(decoded) != string(data) {
+		t.Errorf("round trip = %q, want %q", decoded, data)
+	}
+}
+
+func TestBinaryBytesLiteral(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    BinaryBytes
+		dialect  Dialect
+		expected string
+	}{
+		{"MySQL", BinaryBytes("Hello"), DialectMySQL, "_binary'Hello'"},
+		{"MySQL - 空", BinaryBytes{}, DialectMySQL, "_binary''"},
+		{"MySQL - 转义", BinaryBytes("a'b\\c\x00"), DialectMySQL, `_binary'a''b\\c\0'`},
+		{"MySQL - 不做文本清理", BinaryBytes("1 UNION SELECT 2 -- x"), DialectMySQL, "_binary'1 UNION SELECT 2 -- x'"},
+		{"NO_BACKSLASH_ESCAPES", BinaryBytes("a'b\\c"), DialectMySQLNoBackslashEscapes, `_binary'a''b\c'`},
+		{"Postgres", BinaryBytes("Hi"), DialectPostgres, `'\x4869'::bytea`},
+		{"SQLServer", BinaryBytes("Hi"), DialectSQLServer, "0x4869"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := literalConfig{dialect: tt.dialect}.literal(tt.input)
+			if err != nil {
+				t.Fatalf("literal(%v) error = %v", tt.input, err)
+			}
+			if result != tt.expected {
+				t.Errorf("literal(%v) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBinaryBytesRoundTrip(t *testing.T) {
+	// 包含引号、反斜杠、控制字符和非 UTF-8 字节的二进制数据
+	data := BinaryBytes("\x00\xff'\\\"\n\r\t-- DROP TABLE users;\x1a\x80\xc3\x28")
+	result, err := Literal(data)
+	if err != nil {
+		t.Fatalf("Literal() error = %v", err)
+	}
+	if !strings.HasPrefix(result, "_binary'") {
+		t.Fatalf("Literal() = %q, want _binary'...'", result)
+	}
+	parsed, err := parseMySQLString(strings.TrimPrefix(result, "_binary"))
+	if err != nil {
+		t.Fatalf("parseMySQLString(%q) error = %v", result, err)
+	}
+	if parsed != string(data) {
+		t.Errorf("round trip = %q, want %q", parsed, data)
+	}
+}
+
+func TestRawMessageRoundTrip(t *testing.T) {
+	tests := []json.RawMessage{
+		json.RawMessage(`{"a": "/* not a comment */", "b": "it's -- fine"}`),
+		json.RawMessage("[1,   2,\n  3]"),
+		json.RawMessage(`"C:\\path"`),
+	}
+
+	for _, raw := range tests {
+		result, err := Literal(raw)
+		if err != nil {
+			t.Fatalf("Literal(%s) error = %v", raw, err)
+		}
+		parsed, err := parseMySQLString(result)
+		if err != nil {
+			t.Fatalf("parseMySQLString(%q) error = %v", result, err)
+		}
+		if parsed != string(raw) {
+			t.Errorf("round trip of %s = %q", raw, parsed)
+		}
+	}
+}
+
+func TestJSONValueLiteral(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    JSONValue
+		dialect  Dialect
+		expected string
+	}{
+		{"简单对象", JSONValue{"k": "v"}, DialectANSI, `'{"k":"v"}'`},
+		{"键按字典序", JSONValue{"b": 2, "a": []int{1, 2}}, DialectANSI, `'{"a":[1,2],"b":2}'`},
+		{"嵌套对象", JSONValue{"o": map[string]interface{}{"x": nil}}, DialectANSI, `'{"o":{"x":null}}'`},
+		{"MySQL - 双引号转义", JSONValue{"k": "v"}, DialectMySQL, `'{\"k\":\"v\"}'`},
+		{"MySQL - 引号和反斜杠", JSONValue{"s": `it's "C:\dir"`}, DialectMySQL, `'{\"s\":\"it''s \\\"C:\\\\dir\\\"\"}'`},
+		{"ANSI - 引号和反斜杠", JSONValue{"s": `it's "C:\dir"`}, DialectANSI, `'{"s":"it''s \"C:\\dir\""}'`},
+		{"不做文本清理", JSONValue{"q": "1 UNION SELECT 2 -- /* x */  y"}, DialectANSI, `'{"q":"1 UNION SELECT 2 -- /* x */  y"}'`},
+		{"nil", nil, DialectMySQL, "NULL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := literalConfig{dialect: tt.dialect}.literal(tt.input)
+			if err != nil {
+				t.Fatalf("literal(%v) error = %v", tt.input, err)
+			}
+			if result != tt.expected {
+				t.Errorf("literal(%v) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+
+	// 解析 MySQL 字面量后得到原始的 JSON
+	m := JSONValue{"note": "line1\nline2\t'x'", "n": 1.5}
+	result, err := Literal(m)
+	if err != nil {
+		t.Fatalf("Literal() error = %v", err)
+	}
+	parsed, err := parseMySQLString(result)
+	if err != nil {
+		t.Fatalf("parseMySQLString(%q) error = %v", result, err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(parsed), &decoded); err != nil || decoded["note"] != m["note"] || decoded["n"] != m["n"] {
+		t.Errorf("round trip of %v = %q, %v", m, parsed, err)
+	}
+
+	if _, err := Literal(JSONValue{"f": func() {}}); err == nil {
+		t.Error("Literal(JSONValue with func) error = nil")
+	}
+}
+
+func TestTypedListLiteral(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		vars     []interface{}
+		dialect  Dialect
+		expected string
+	}{
+		{
+			name:     "按ID验证每个元素",
+			sql:      "SELECT * FROM t WHERE id IN (?)",
+			vars:     []interface{}{TypedList{Type: ParamTypeID, Values: []string{"abc def", "1' OR '1'='1", "u-01"}}},
+			expected: "SELECT * FROM t WHERE id IN ('abc_def','1__OR__1___1','u-01')",
+		},
+		{
+			name:     "空列表",
+			sql:      "SELECT * FROM t WHERE id IN (?)",
+			vars:     []interface{}{TypedList{Type: ParamTypeID}},
+			expected: "SELECT * FROM t WHERE id IN (NULL)",
+		},
+		{
+			name:     "按方言转义",
+			sql:      "SELECT * FROM t WHERE name IN (?) AND id = ?",
+			vars:     []interface{}{TypedList{Type: ParamTypeName, Values: []string{"O'Neil", `a\b`}}, 1},
+			dialect:  DialectANSI,
+			expected: `SELECT * FROM t WHERE name IN ('O''Neil','a\b') AND id = 1`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ExpandFor(tt.sql, tt.vars, tt.dialect)
+			if err != nil {
+				t.Fatalf("ExpandFor(%q) error = %v", tt.sql, err)
+			}
+			if result != tt.expected {
+				t.Errorf("ExpandFor(%q) = %q, want %q", tt.sql, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNumericLiteral(t *testing.T) {
+	valid := []struct {
+		input    Numeric
+		expected string
+	}{
+		{"12345.6789", "12345.6789"},
+		{"-0.01", "-0.01"},
+		{"+7", "+7"},
+		{"100", "100"},
+		{".5", ".5"},
+		{"5.", "5."},
+		{"1.5e-10", "1.5e-10"},
+		{"123456789012345678901234567890.123456789", "123456789012345678901234567890.123456789"},
+	}
+	for _, tt := range valid {
+		result, err := Literal(tt.input)
+		if err != nil {
+			t.Errorf("Literal(Numeric(%q)) error = %v", tt.input, err)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("Literal(Numeric(%q)) = %q, want %q", tt.input, result, tt.expected)
+		}
+	}
+
+	invalid := []Numeric{"", "-", ".", "1.2.3", "1e", "0x1F", "NaN", "1,000", " 1", "1 OR 1=1", "1; DROP TABLE t", "١٢٣"}
+	for _, input := range invalid {
+		if result, err := Literal(input); err == nil {
+			t.Errorf("Literal(Numeric(%q)) = %q, want error", input, result)
+		}
+	}
+
+	result, err := Expand("UPDATE accounts SET balance = ? WHERE id IN (?)", []interface{}{Numeric("99.95"), []Numeric{"1", "2"}})
+	if err != nil {
+		t.Fatalf("Expand() error = %v", err)
+	}
+	if want := "UPDATE accounts SET balance = 99.95 WHERE id IN (1,2)"; result != want {
+		t.Errorf("Expand() = %q, want %q", result, want)
+	}
+}
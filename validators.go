@@ -0,0 +1,414 @@
+package sqlhelper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	// ErrInvalidJSON 严格模式下 JSONValidator 遇到非法 JSON 时返回的错误
+	ErrInvalidJSON = errors.New("不是合法的 JSON")
+	// ErrInvalidEmail 严格模式下 EmailValidator 遇到非法邮箱时返回的错误
+	ErrInvalidEmail = errors.New("不是合法的邮箱地址")
+	// ErrInvalidPhone 严格模式下 PhoneValidator 遇到非法电话号码时返回的错误
+	ErrInvalidPhone = errors.New("不是合法的电话号码")
+	// ErrInvalidURL 严格模式下 URLValidator 遇到非法或协议不被允许的 URL 时返回的错误
+	ErrInvalidURL = errors.New("不是合法的 URL")
+	// ErrInvalidNumericString 严格模式下 NumericStringValidator 遇到非数字字符或位数不足时返回的错误
+	ErrInvalidNumericString = errors.New("不是合法的数字字符串")
+	// ErrNotAllowed 严格模式下 WhitelistValidator 遇到不在白名单中的值时返回的错误
+	ErrNotAllowed = errors.New("不在允许的取值范围内")
+)
+
+// JSONValidator JSON类型验证器，合法的 JSON 逐字节原样保留
+// 引号转义已足以保证 JSON 文本的安全，改写其中的 /*、空白等只会破坏数据
+type JSONValidator struct{}
+
+func (v JSONValidator) GetType() ParamType {
+	return ParamTypeJSON
+}
+
+// Validate 合法 JSON 原样返回；非法时退化为 GenericValidator 的处理
+func (v JSONValidator) Validate(value string) string {
+	if json.Valid([]byte(value)) {
+		return value
+	}
+	return GenericValidator{}.Validate(value)
+}
+
+// ValidateStrict 合法 JSON 原样返回；非法时返回 ErrInvalidJSON
+func (v JSONValidator) ValidateStrict(value string) (string, error) {
+	if !json.Valid([]byte(value)) {
+		return "", ErrInvalidJSON
+	}
+	return value, nil
+}
+
+// emailMaxLength 邮箱地址的最大长度（RFC 5321）
+const emailMaxLength = 254
+
+// emailRe 邮箱地址的基本格式：本地部分@至少两级的域名
+var emailRe = regexp.MustCompile(`^[A-Za-z0-9._%+\-]+@[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?(?:\.[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?)+$`)
+
+// EmailValidator 邮箱类型验证器，规范化后校验邮箱格式
+// 格式不合法时仍返回清理后的字符串，调用方可通过 IsValid 决定是否拒绝
+type EmailValidator struct{}
+
+func (v EmailValidator) GetType() ParamType {
+	return ParamTypeEmail
+}
+
+// Validate 规范化邮箱：Unicode规范化、去掉首尾空白、域名部分转小写
+// 格式不合法时再经过 GenericValidator 清理
+func (v EmailValidator) Validate(value string) string {
+	normalized := v.normalize(value)
+	if v.valid(normalized) {
+		return normalized
+	}
+	return GenericValidator{}.Validate(normalized)
+}
+
+// ValidateStrict 格式不合法时返回 ErrInvalidEmail
+func (v EmailValidator) ValidateStrict(value string) (string, error) {
+	normalized := v.normalize(value)
+	if !v.valid(normalized) {
+		return "", ErrInvalidEmail
+	}
+	return normalized, nil
+}
+
+// IsValid 报告规范化后的 value 是否为格式合法的邮箱地址
+func (v EmailValidator) IsValid(value string) bool {
+	return v.valid(v.normalize(value))
+}
+
+func (v EmailValidator) normalize(value string) string {
+	normalized := strings.TrimSpace(norm.NFKC.String(value))
+	if at := strings.LastIndexByte(normalized, '@'); at >= 0 {
+		normalized = normalized[:at+1] + strings.ToLower(normalized[at+1:])
+	}
+	return normalized
+}
+
+func (v EmailValidator) valid(normalized string) bool {
+	return len(normalized) <= emailMaxLength && emailRe.MatchString(normalized)
+}
+
+// 电话号码的数字个数范围，E.164 规定最多 15 位
+const (
+	phoneMinDigits = 7
+	phoneMaxDigits = 15
+)
+
+// PhoneValidator 电话类型验证器，把各种书写格式规范化为 E.164 形式（+国家码号码）
+// 输出只包含数字和开头的 +，最多保留 E.164 规定的 15 位数字；数字个数不合法时 Validate 仍返回清理后的字符串，
+// 调用方可通过 IsValid 决定是否拒绝
+type PhoneValidator struct {
+	// DefaultCountryCode 输入不以 + 或 00 国际冠码开头时补在前面的国家码（只含数字，如 "86"），为空时不补
+	// 补国家码时去掉国内长途前缀 0，如 "44" 下的 020 7946 0018 规范化为 +442079460018
+	DefaultCountryCode string
+	// ErrorOnOverflow 为 true 时 ValidateStrict 对超过 15 位数字的输入返回 *LengthError 而不是 ErrInvalidPhone；Validate 总是截断
+	ErrorOnOverflow bool
+}
+
+// NewPhoneValidator 创建使用默认国家码的电话验证器
+func NewPhoneValidator(defaultCountryCode string) PhoneValidator {
+	return PhoneValidator{DefaultCountryCode: strings.TrimPrefix(defaultCountryCode, "+")}
+}
+
+func (v PhoneValidator) GetType() ParamType {
+	return ParamTypePhone
+}
+
+// Validate 去掉空格、短横线、括号等非数字字符，保留开头的 +，开头的 00 国际冠码改写为 +
+// 没有 + 且设置了 DefaultCountryCode 时补上 +国家码；超过 15 位的数字被截断
+func (v PhoneValidator) Validate(value string) string {
+	normalized, digits := v.normalize(value)
+	if digits > phoneMaxDigits {
+		normalized = normalized[:len(normalized)-(digits-phoneMaxDigits)]
+	}
+	return normalized
+}
+
+// ValidateStrict 规范化后数字个数不在 7 到 15 位之间时返回 ErrInvalidPhone，
+// 开启 ErrorOnOverflow 时超过 15 位返回 *LengthError
+func (v PhoneValidator) ValidateStrict(value string) (string, error) {
+	normalized, digits := v.normalize(value)
+	if digits > phoneMaxDigits && v.ErrorOnOverflow {
+		return "", &LengthError{Type: ParamTypePhone, Length: digits, MaxLength: phoneMaxDigits}
+	}
+	if !validPhoneDigits(digits) {
+		return "", ErrInvalidPhone
+	}
+	return normalized, nil
+}
+
+// IsValid 报告规范化后的 value 是否为数字个数合法的电话号码
+func (v PhoneValidator) IsValid(value string) bool {
+	_, digits := v.normalize(value)
+	return validPhoneDigits(digits)
+}
+
+// normalize 返回规范化但未截断的号码及其中的数字个数（含国家码）
+func (v PhoneValidator) normalize(value string) (string, int) {
+	normalized := strings.TrimSpace(norm.NFKC.String(value))
+	plus := strings.HasPrefix(normalized, "+")
+
+	digits := make([]byte, 0, len(normalized))
+	for i := 0; i < len(normalized); i++ {
+		if c := normalized[i]; c >= '0' && c <= '9' {
+			digits = append(digits, c)
+		}
+	}
+	var prefix string
+	switch {
+	case plus:
+		prefix = "+"
+	case len(digits) >= 2 && digits[0] == '0' && digits[1] == '0':
+		// 00 国际冠码后面已经是国家码
+		prefix, digits = "+", digits[2:]
+	case v.DefaultCountryCode != "":
+		prefix = "+" + v.DefaultCountryCode
+		if len(digits) > 0 && digits[0] == '0' {
+			digits = digits[1:] // 国内长途前缀
+		}
+	}
+	n := len(digits)
+	if prefix != "" {
+		n += len(prefix) - 1 // 国家码的数字，不含 +
+	}
+	return prefix + string(digits), n
+}
+
+func validPhoneDigits(digits int) bool {
+	return digits >= phoneMinDigits && digits <= phoneMaxDigits
+}
+
+// urlMaxLength URL 的最大长度，超出的 URL 视为不合法
+const urlMaxLength = 2048
+
+// URLValidator URL类型验证器，解析 URL 并输出规范形式
+// 只接受带主机名的绝对 URL 和允许的协议，拒绝 javascript:、data: 等能在网页中执行脚本的协议；
+// 规范形式中协议和主机名为小写，去掉片段（# 之后的部分）和用户信息。不合法时 Validate 返回空字符串，
+// 调用方可通过 IsValid 决定是否拒绝
+type URLValidator struct {
+	// AllowedSchemes 允许的协议，不区分大小写，为空时只允许 http 和 https
+	AllowedSchemes []string
+}
+
+func (v URLValidator) GetType() ParamType {
+	return ParamTypeURL
+}
+
+// Validate 返回 URL 的规范形式，不合法或协议不被允许时返回空字符串
+func (v URLValidator) Validate(value string) string {
+	canonical, _ := v.canonical(value)
+	return canonical
+}
+
+// ValidateStrict 不合法或协议不被允许时返回 ErrInvalidURL
+func (v URLValidator) ValidateStrict(value string) (string, error) {
+	canonical, ok := v.canonical(value)
+	if !ok {
+		return "", ErrInvalidURL
+	}
+	return canonical, nil
+}
+
+// IsValid 报告 value 是否为协议被允许的合法 URL
+func (v URLValidator) IsValid(value string) bool {
+	_, ok := v.canonical(value)
+	return ok
+}
+
+// canonical 解析 value 并返回规范形式，不合法时返回 false
+func (v URLValidator) canonical(value string) (string, bool) {
+	normalized := strings.TrimSpace(norm.NFKC.String(value))
+	if len(normalized) > urlMaxLength {
+		return "", false
+	}
+	u, err := url.Parse(normalized)
+	if err != nil || u.Opaque != "" || !v.allowed(u.Scheme) || !validHost(u.Hostname()) {
+		return "", false
+	}
+	// 只取路径和查询参数重新拼接，不含用户信息和片段；主机名单独拼接，避免国际化域名被转义为 %XX
+	rest := &url.URL{Path: u.Path, RawPath: u.RawPath, RawQuery: u.RawQuery, ForceQuery: u.ForceQuery}
+	return u.Scheme + "://" + strings.ToLower(u.Host) + rest.String(), true
+}
+
+// allowed 判断协议是否被允许，url.Parse 已把协议转为小写
+func (v URLValidator) allowed(scheme string) bool {
+	if len(v.AllowedSchemes) == 0 {
+		return scheme == "http" || scheme == "https"
+	}
+	for _, s := range v.AllowedSchemes {
+		if strings.EqualFold(s, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// validHost 判断 host 是否为 IP 地址，或只由字母、数字、短横线和点组成的域名（允许国际化域名）
+func validHost(host string) bool {
+	if host == "" {
+		return false
+	}
+	if net.ParseIP(host) != nil {
+		return true
+	}
+	for _, r := range host {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// NumericStringValidator 数字字符串验证器，用于账号、证件号等以字符串存储、可能有前导零的数字
+// 只保留 0-9（全角数字先规范化为半角），前导零原样保留，不做空白合并等文本处理
+type NumericStringValidator struct {
+	// MinLength 最少位数，只在 ValidateStrict 和 IsValid 中检查，0 表示不限制
+	MinLength int
+	// MaxLength 最多位数，0 使用默认值 DefaultNumericStringMaxLength，NoLengthLimit 表示不限制
+	MaxLength int
+}
+
+func (v NumericStringValidator) GetType() ParamType {
+	return ParamTypeNumericString
+}
+
+// WithMaxLength 实现 LengthLimiter
+func (v NumericStringValidator) WithMaxLength(maxLen int) ParamValidator {
+	v.MaxLength = resolveMaxLength(maxLen)
+	return v
+}
+
+// Validate 去掉字母、空白、短横线等所有非数字字符，超出 MaxLength 时截断
+func (v NumericStringValidator) Validate(value string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKC.String(value) {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return limitLength(b.String(), v.MaxLength, DefaultNumericStringMaxLength)
+}
+
+// ValidateStrict 去掉首尾空白后含有非数字字符，或位数少于 MinLength 时返回 ErrInvalidNumericString；
+// 位数超过 MaxLength 时返回 *LengthError
+func (v NumericStringValidator) ValidateStrict(value string) (string, error) {
+	normalized := strings.TrimSpace(norm.NFKC.String(value))
+	for i := 0; i < len(normalized); i++ {
+		if c := normalized[i]; c < '0' || c > '9' {
+			return "", ErrInvalidNumericString
+		}
+	}
+	if len(normalized) < v.MinLength {
+		return "", fmt.Errorf("%w：%d 位，至少需要 %d 位", ErrInvalidNumericString, len(normalized), v.MinLength)
+	}
+	return strictLimit(normalized, v.MaxLength, DefaultNumericStringMaxLength, true, ParamTypeNumericString)
+}
+
+// IsValid 报告 value 是否可以通过 ValidateStrict
+func (v NumericStringValidator) IsValid(value string) bool {
+	_, err := v.ValidateStrict(value)
+	return err == nil
+}
+
+// WhitelistValidator 白名单验证器，只接受固定集合中的值，适用于状态、枚举等封闭取值的列
+// 比较时忽略大小写和首尾空白，命中时返回白名单中的规范写法，未命中时返回空字符串
+type WhitelistValidator struct {
+	// Type 验证器对应的参数类型，由 NewWhitelistValidator 设置；RegisterValidator 按它决定替换哪个类型的验证器
+	Type ParamType
+
+	canonical map[string]string // 规范化后的值 -> 白名单中的写法
+}
+
+// NewWhitelistValidator 创建只接受 allowed 中取值、用于参数类型 paramType 的验证器
+// 白名单通常对应一个自定义类型（见 NewParamType）；传入 ParamTypeGeneric 并注册会让所有通用字符串都按白名单过滤
+func NewWhitelistValidator(paramType ParamType, allowed []string) WhitelistValidator {
+	canonical := make(map[string]string, len(allowed))
+	for _, a := range allowed {
+		key := whitelistKey(a)
+		if _, exists := canonical[key]; !exists {
+			canonical[key] = a
+		}
+	}
+	return WhitelistValidator{Type: paramType, canonical: canonical}
+}
+
+func (v WhitelistValidator) GetType() ParamType {
+	return v.Type
+}
+
+// Validate 命中白名单时返回规范写法，否则返回空字符串
+func (v WhitelistValidator) Validate(value string) string {
+	return v.canonical[whitelistKey(value)]
+}
+
+// ValidateStrict 未命中白名单时返回 ErrNotAllowed
+func (v WhitelistValidator) ValidateStrict(value string) (string, error) {
+	canonical, ok := v.canonical[whitelistKey(value)]
+	if !ok {
+		return "", ErrNotAllowed
+	}
+	return canonical, nil
+}
+
+// whitelistKey 白名单比较用的键：Unicode规范化、去掉首尾空白并转小写
+func whitelistKey(s string) string {
+	return strings.ToLower(strings.TrimSpace(norm.NFKC.String(s)))
+}
+
+// ChainValidator 依次执行多个验证器的组合验证器，前一个的输出作为后一个的输入
+// 例如先按 ID 清理再检查白名单，或在名称验证之后再做额外处理，无需为每种组合单独实现验证器
+type ChainValidator struct {
+	// Type 验证器对应的参数类型，NewChainValidator 默认取第一个验证器的类型
+	Type ParamType
+	// Validators 按顺序执行的验证器
+	Validators []ParamValidator
+}
+
+// NewChainValidator 创建依次执行 validators 的组合验证器，参数类型取第一个验证器的类型
+func NewChainValidator(validators ...ParamValidator) ChainValidator {
+	v := ChainValidator{Validators: validators}
+	if len(validators) > 0 {
+		v.Type = validators[0].GetType()
+	}
+	return v
+}
+
+func (v ChainValidator) GetType() ParamType {
+	return v.Type
+}
+
+func (v ChainValidator) Validate(value string) string {
+	for _, validator := range v.Validators {
+		value = validator.Validate(value)
+	}
+	return value
+}
+
+// ValidateStrict 依次执行各验证器，支持严格模式的使用 ValidateStrict，返回第一个出现的错误
+func (v ChainValidator) ValidateStrict(value string) (string, error) {
+	for _, validator := range v.Validators {
+		if strict, ok := validator.(StrictValidator); ok {
+			var err error
+			if value, err = strict.ValidateStrict(value); err != nil {
+				return "", err
+			}
+			continue
+		}
+		value = validator.Validate(value)
+	}
+	return value, nil
+}